@@ -70,6 +70,10 @@ func addMeshtasticHook(server *mqtt.Server, cfg domain.Config, f *factory.Factor
 		TopicPrefix:  prometheusConfig.GetTopicPattern(),
 		MetricsTTL:   prometheusConfig.GetMetricsTTL(),
 	}
+	if processing, ok := prometheusConfig.(domain.PrometheusProcessingConfig); ok {
+		hookConfig.ProtobufPattern = processing.GetProtobufPattern()
+		hookConfig.MapReportPattern = processing.GetMapReportPattern()
+	}
 
 	alertConfig := cfg.GetAlertManagerConfig()
 	hookConfig.AlertPath = alertConfig.GetPath()
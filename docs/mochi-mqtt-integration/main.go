@@ -5,6 +5,8 @@
 package main
 
 import (
+	"encoding/binary"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -14,11 +16,38 @@ import (
 	"github.com/mochi-mqtt/server/v2/hooks/auth"
 	"github.com/mochi-mqtt/server/v2/listeners"
 
+	"meshtastic-exporter/pkg/application"
 	"meshtastic-exporter/pkg/config"
+	"meshtastic-exporter/pkg/domain"
 	"meshtastic-exporter/pkg/factory"
 	"meshtastic-exporter/pkg/hooks"
+	"meshtastic-exporter/pkg/meshpb"
 )
 
+// privateSensorType message type of PRIVATE_APP packets; CollectTelemetry
+// counts them in meshtastic_messages_total as telemetry
+const privateSensorType = "private_sensor"
+
+// handlePrivateSensor decodes PRIVATE_APP (256) payload of a custom sensor:
+// int16 big-endian temperature in 0.01 °C followed by uint8 relative humidity.
+func handlePrivateSensor(collector domain.MetricsCollector, nodeID string, msg domain.MeshtasticMessage) error {
+	if len(msg.RawPayload) < 3 {
+		return fmt.Errorf("private sensor payload too short: %d bytes", len(msg.RawPayload))
+	}
+	temperature := float64(int16(binary.BigEndian.Uint16(msg.RawPayload))) / 100
+	humidity := float64(msg.RawPayload[2])
+
+	return collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:           nodeID,
+		Type:             domain.TelemetryTypeEnvironment,
+		RSSI:             msg.RSSI,
+		SNR:              msg.SNR,
+		Timestamp:        msg.ReceivedAt,
+		Temperature:      &temperature,
+		RelativeHumidity: &humidity,
+	})
+}
+
 func main() {
 	// Create mochi-mqtt server
 	server := mqtt.New(&mqtt.Options{
@@ -33,6 +62,13 @@ func main() {
 	}
 	f := factory.NewFactory(cfg)
 
+	// Decode our own PRIVATE_APP packets without forking the exporter
+	handlers := application.NewHandlerRegistry()
+	if err := handlers.RegisterPort(meshpb.PortNumPrivate, privateSensorType, handlePrivateSensor); err != nil {
+		log.Fatalf("Failed to register PRIVATE_APP handler: %v", err)
+	}
+	f.SetMessageHandlers(handlers)
+
 	// Add Meshtastic Prometheus hook - simple version
 	meshtasticHook := hooks.NewMeshtasticHookSimple(f)
 
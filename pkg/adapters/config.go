@@ -2,9 +2,12 @@ package adapters
 
 import (
 	"fmt"
+	"regexp"
 	"time"
 
 	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/meshpb"
+	"meshtastic-exporter/pkg/validator"
 )
 
 type ConfigAdapter struct {
@@ -48,12 +51,23 @@ type UserAuthAdapter struct {
 }
 
 type PrometheusConfigAdapter struct {
-	Listen         string
-	Path           string
-	MetricsTTL     time.Duration
-	TopicPattern   string
-	LogAllMessages bool
-	StateFile      string
+	Listen          string
+	Path            string
+	MetricsTTL      time.Duration
+	TopicPattern    string
+	ProtobufPattern string
+	// MapReportPattern топики с MAP_REPORT_APP, пустой паттерн отключает разбор карты
+	MapReportPattern string
+	TopicTemplate    string
+	ChannelKeys      map[string]string
+	DedupWindow      time.Duration
+	NodeIDFormat     string
+	DerivedMetrics   bool
+	BatteryModel     domain.BatteryModelConfig
+	PacketLoss       domain.PacketLossConfig
+	Mappings         []domain.MetricMapping
+	LogAllMessages   bool
+	StateFile        string
 }
 
 type AlertManagerConfigAdapter struct {
@@ -110,6 +124,33 @@ func (c *ConfigAdapter) Validate() error {
 	if c.alertManager.Listen == "" {
 		return fmt.Errorf("alertmanager listen address cannot be empty")
 	}
+	return c.prometheus.validate()
+}
+
+// validate проверяет настройки разбора сообщений и производных метрик.
+func (p *PrometheusConfigAdapter) validate() error {
+	if _, err := validator.ParseTopicTemplate(p.TopicTemplate); err != nil {
+		return fmt.Errorf("invalid topic template: %w", err)
+	}
+	if format := p.NodeIDFormat; format != "" && !domain.IsValidNodeIDFormat(format) {
+		return fmt.Errorf("invalid node_id_format: %s", format)
+	}
+	if err := validateBatteryModel(p.BatteryModel); err != nil {
+		return err
+	}
+	if err := validateMappings(p.Mappings); err != nil {
+		return err
+	}
+	for stream, interval := range p.PacketLoss.Intervals {
+		if interval <= 0 {
+			return fmt.Errorf("invalid packet_loss interval for %s", stream)
+		}
+	}
+	for channel, psk := range p.ChannelKeys {
+		if _, err := meshpb.ParsePSK(psk); err != nil {
+			return fmt.Errorf("invalid key for channel %s: %w", channel, err)
+		}
+	}
 	return nil
 }
 
@@ -146,15 +187,90 @@ func (m *MQTTConfigAdapter) GetUsers() []domain.UserAuth {
 func (u *UserAuthAdapter) GetUsername() string { return u.Username }
 func (u *UserAuthAdapter) GetPassword() string { return u.Password }
 
-func (p *PrometheusConfigAdapter) GetListen() string            { return p.Listen }
-func (p *PrometheusConfigAdapter) GetPath() string              { return p.Path }
-func (p *PrometheusConfigAdapter) GetMetricsTTL() time.Duration { return p.MetricsTTL }
-func (p *PrometheusConfigAdapter) GetTopicPattern() string      { return p.TopicPattern }
-func (p *PrometheusConfigAdapter) GetLogAllMessages() bool      { return p.LogAllMessages }
-func (p *PrometheusConfigAdapter) GetStateFile() string         { return p.StateFile }
+func (p *PrometheusConfigAdapter) GetListen() string                          { return p.Listen }
+func (p *PrometheusConfigAdapter) GetPath() string                            { return p.Path }
+func (p *PrometheusConfigAdapter) GetMetricsTTL() time.Duration               { return p.MetricsTTL }
+func (p *PrometheusConfigAdapter) GetTopicPattern() string                    { return p.TopicPattern }
+func (p *PrometheusConfigAdapter) GetProtobufPattern() string                 { return p.ProtobufPattern }
+func (p *PrometheusConfigAdapter) GetMapReportPattern() string                { return p.MapReportPattern }
+func (p *PrometheusConfigAdapter) GetTopicTemplate() string                   { return p.TopicTemplate }
+func (p *PrometheusConfigAdapter) GetChannelKeys() map[string]string          { return p.ChannelKeys }
+func (p *PrometheusConfigAdapter) GetDedupWindow() time.Duration              { return p.DedupWindow }
+func (p *PrometheusConfigAdapter) GetNodeIDFormat() string                    { return p.NodeIDFormat }
+func (p *PrometheusConfigAdapter) GetDerivedMetrics() bool                    { return p.DerivedMetrics }
+func (p *PrometheusConfigAdapter) GetBatteryModel() domain.BatteryModelConfig { return p.BatteryModel }
+func (p *PrometheusConfigAdapter) GetPacketLoss() domain.PacketLossConfig     { return p.PacketLoss }
+func (p *PrometheusConfigAdapter) GetMetricMappings() []domain.MetricMapping  { return p.Mappings }
+func (p *PrometheusConfigAdapter) GetLogAllMessages() bool                    { return p.LogAllMessages }
+func (p *PrometheusConfigAdapter) GetStateFile() string                       { return p.StateFile }
 
 func (a *AlertManagerConfigAdapter) GetListen() string       { return a.Listen }
 func (a *AlertManagerConfigAdapter) GetPath() string         { return a.Path }
 func (a *AlertManagerConfigAdapter) GetMQTTTopic() string    { return a.MQTTTopic }
 func (a *AlertManagerConfigAdapter) GetFromNodeID() uint32   { return a.FromNodeID }
 func (a *AlertManagerConfigAdapter) GetRouting() interface{} { return a.Routing }
+
+func validateBatteryModel(battery domain.BatteryModelConfig) error {
+	for name, curve := range battery.Curves {
+		if !curve.Valid() {
+			return fmt.Errorf("invalid battery curve %s: need at least 2 strictly ascending voltages", name)
+		}
+	}
+	known := func(chemistry string) bool {
+		_, builtin := domain.BatteryCurves[chemistry]
+		_, custom := battery.Curves[chemistry]
+		return builtin || custom
+	}
+	if battery.Chemistry != "" && !known(battery.Chemistry) {
+		return fmt.Errorf("unknown battery chemistry: %s", battery.Chemistry)
+	}
+	for nodeID, chemistry := range battery.NodeChemistry {
+		if !known(chemistry) {
+			return fmt.Errorf("unknown battery chemistry %s for node %s", chemistry, nodeID)
+		}
+	}
+	return nil
+}
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+func validateMappings(mappings []domain.MetricMapping) error {
+	signatures := make(map[string]string, len(mappings))
+	for i, mapping := range mappings {
+		if err := validateMapping(mapping); err != nil {
+			return fmt.Errorf("invalid mapping %d: %w", i, err)
+		}
+		// одно имя из разных маппингов допустимо, если совпадают тип и лейблы
+		name := mapping.MetricName()
+		signature := mapping.Signature()
+		if previous, exists := signatures[name]; exists && previous != signature {
+			return fmt.Errorf("invalid mapping %d: metric %s declared as %s and %s", i, name, previous, signature)
+		}
+		signatures[name] = signature
+	}
+	return nil
+}
+
+func validateMapping(mapping domain.MetricMapping) error {
+	if mapping.MessageType == "" {
+		return fmt.Errorf("message_type is required")
+	}
+	if mapping.Type != domain.MappingTypeGauge && mapping.Type != domain.MappingTypeCounter {
+		return fmt.Errorf("unknown type %q", mapping.Type)
+	}
+	if mapping.Path == "" && !mapping.IsCounter() {
+		return fmt.Errorf("path is required for gauge")
+	}
+	if !metricNamePattern.MatchString(mapping.MetricName()) {
+		return fmt.Errorf("invalid metric name %q", mapping.MetricName())
+	}
+	for label, path := range mapping.Labels {
+		if !labelNamePattern.MatchString(label) || label == "node_id" || path == "" {
+			return fmt.Errorf("invalid label %q", label)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package application
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+type batterySample struct {
+	at  time.Time
+	soc float64
+}
+
+// batteryModel оценивает заряд по кривой напряжения и время до разряда по наклону
+// заряда за скользящее окно. battery_level прошивки грубый и равен 101 при внешнем питании.
+type batteryModel struct {
+	mu         sync.Mutex
+	window     time.Duration
+	curve      domain.BatteryCurve
+	nodeCurves map[string]domain.BatteryCurve // nodeID -> кривая
+	history    map[string][]batterySample
+}
+
+func newBatteryModel(cfg domain.BatteryModelConfig, nodeIDFormat string) *batteryModel {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	curves := make(map[string]domain.BatteryCurve, len(domain.BatteryCurves)+len(cfg.Curves))
+	for name, curve := range domain.BatteryCurves {
+		curves[name] = curve
+	}
+	for name, curve := range cfg.Curves {
+		curves[name] = curve
+	}
+
+	b := &batteryModel{
+		window:     cfg.Window,
+		curve:      curves[domain.BatteryChemistryLiIon],
+		nodeCurves: make(map[string]domain.BatteryCurve),
+		history:    make(map[string][]batterySample),
+	}
+	if b.window <= 0 {
+		b.window = domain.DefaultBatteryWindow
+	}
+	if curve, ok := curves[cfg.Chemistry]; ok {
+		b.curve = curve
+	}
+	for nodeID, chemistry := range cfg.NodeChemistry {
+		if curve, ok := curves[chemistry]; ok {
+			b.nodeCurves[domain.ConvertNodeID(nodeID, nodeIDFormat)] = curve
+		}
+	}
+	return b
+}
+
+// apply заполняет data.Battery, если в телеметрии устройства есть напряжение. voltage
+// environment_metrics — напряжение шины датчика INA, к заряду батареи оно не относится.
+func (b *batteryModel) apply(data *domain.TelemetryData, now time.Time) {
+	if b == nil || data.Type != domain.TelemetryTypeDevice || data.Voltage == nil || *data.Voltage <= 0 {
+		return
+	}
+
+	estimate := &domain.BatteryEstimate{
+		StateOfCharge: roundToTwoDecimals(b.curveFor(data.NodeID).StateOfCharge(*data.Voltage)),
+		ExternalPower: data.BatteryLevel != nil && *data.BatteryLevel >= domain.BatteryPoweredLevel,
+	}
+	data.Battery = estimate
+
+	if estimate.ExternalPower {
+		// на внешнем питании напряжение не отражает заряд, историю начинаем заново.
+		// Заряжается ли батарея (и есть ли она вообще), отсюда не видно
+		b.reset(data.NodeID)
+		return
+	}
+
+	slope, ok := b.observe(data.NodeID, batterySample{at: now, soc: estimate.StateOfCharge})
+	if !ok {
+		return
+	}
+	estimate.Charging = slope > domain.BatterySlopeThreshold
+	if slope < -domain.BatterySlopeThreshold {
+		hours := estimate.StateOfCharge / -slope
+		timeToEmpty := time.Duration(hours * float64(time.Hour))
+		estimate.TimeToEmpty = &timeToEmpty
+	}
+}
+
+func (b *batteryModel) curveFor(nodeID string) domain.BatteryCurve {
+	if curve, ok := b.nodeCurves[nodeID]; ok {
+		return curve
+	}
+	return b.curve
+}
+
+func (b *batteryModel) reset(nodeID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.history, nodeID)
+}
+
+// observe добавляет замер и возвращает наклон заряда в %/ч по методу наименьших квадратов.
+// Замеры хранятся по времени: пакет от медленного шлюза может прийти позже более нового.
+func (b *batteryModel) observe(nodeID string, sample batterySample) (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	samples := b.history[nodeID]
+	position := sort.Search(len(samples), func(i int) bool { return samples[i].at.After(sample.at) })
+	samples = slices.Insert(samples, position, sample)
+	cutoff := samples[len(samples)-1].at.Add(-b.window)
+	start := 0
+	for start < len(samples) && samples[start].at.Before(cutoff) {
+		start++
+	}
+	samples = samples[start:]
+	b.history[nodeID] = samples
+
+	if len(samples) < domain.BatteryMinSamples {
+		return 0, false
+	}
+	return socSlope(samples)
+}
+
+func socSlope(samples []batterySample) (float64, bool) {
+	origin := samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.at.Sub(origin).Hours()
+		sumX += x
+		sumY += s.soc
+		sumXY += x * s.soc
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
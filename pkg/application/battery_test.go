@@ -0,0 +1,170 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+func batteryTelemetry(nodeID string, voltage, level float64) *domain.TelemetryData {
+	return &domain.TelemetryData{NodeID: nodeID, Type: domain.TelemetryTypeDevice, Voltage: &voltage, BatteryLevel: &level}
+}
+
+func TestBatteryModel_Discharging(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{Enabled: true}, domain.NodeIDFormatDecimal)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 3.99 В = 80%, 3.89 В = 70%, 3.80 В = 60%: 10% в час
+	var data *domain.TelemetryData
+	for i, voltage := range []float64{3.99, 3.89, 3.80} {
+		data = batteryTelemetry("123", voltage, 60)
+		model.apply(data, start.Add(time.Duration(i)*time.Hour))
+	}
+
+	require.NotNil(t, data.Battery)
+	assert.InDelta(t, 60, data.Battery.StateOfCharge, 0.01)
+	assert.False(t, data.Battery.ExternalPower)
+	assert.False(t, data.Battery.Charging)
+	require.NotNil(t, data.Battery.TimeToEmpty)
+	assert.InDelta(t, 6, data.Battery.TimeToEmpty.Hours(), 0.01)
+}
+
+func TestBatteryModel_NotEnoughHistory(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{Enabled: true}, domain.NodeIDFormatDecimal)
+	now := time.Now()
+
+	data := batteryTelemetry("123", 3.99, 80)
+	model.apply(data, now)
+	data = batteryTelemetry("123", 3.89, 70)
+	model.apply(data, now.Add(time.Hour))
+
+	require.NotNil(t, data.Battery)
+	assert.Nil(t, data.Battery.TimeToEmpty)
+
+	// замеры старше окна не учитываются
+	data = batteryTelemetry("123", 3.80, 60)
+	model.apply(data, now.Add(8*time.Hour))
+	assert.Nil(t, data.Battery.TimeToEmpty)
+}
+
+func TestBatteryModel_OutOfOrderSamples(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{Enabled: true}, domain.NodeIDFormatDecimal)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// замер за 1h пришёл через медленный шлюз последним
+	model.apply(batteryTelemetry("123", 3.99, 60), start)
+	model.apply(batteryTelemetry("123", 3.80, 60), start.Add(2*time.Hour))
+	late := batteryTelemetry("123", 3.89, 60)
+	model.apply(late, start.Add(time.Hour))
+
+	require.NotNil(t, late.Battery.TimeToEmpty)
+	assert.InDelta(t, 7, late.Battery.TimeToEmpty.Hours(), 0.01)
+	history := model.history["123"]
+	require.Len(t, history, 3)
+	assert.Equal(t, start.Add(time.Hour), history[1].at)
+
+	// замер старше окна не вытесняет новые
+	model.apply(batteryTelemetry("123", 4.1, 60), start.Add(-12*time.Hour))
+	assert.Len(t, model.history["123"], 3)
+}
+
+func TestBatteryModel_Charging(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{Enabled: true}, domain.NodeIDFormatDecimal)
+	start := time.Now()
+
+	var data *domain.TelemetryData
+	for i, voltage := range []float64{3.63, 3.72, 3.80} {
+		data = batteryTelemetry("123", voltage, 50)
+		model.apply(data, start.Add(time.Duration(i)*30*time.Minute))
+	}
+
+	assert.True(t, data.Battery.Charging)
+	assert.Nil(t, data.Battery.TimeToEmpty)
+}
+
+func TestBatteryModel_ExternalPower(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{Enabled: true}, domain.NodeIDFormatDecimal)
+	start := time.Now()
+
+	for i, voltage := range []float64{3.99, 3.89} {
+		model.apply(batteryTelemetry("123", voltage, 60), start.Add(time.Duration(i)*time.Hour))
+	}
+	data := batteryTelemetry("123", 4.25, domain.BatteryPoweredLevel)
+	model.apply(data, start.Add(2*time.Hour))
+
+	assert.True(t, data.Battery.ExternalPower)
+	assert.False(t, data.Battery.Charging)
+	assert.InDelta(t, 100, data.Battery.StateOfCharge, 0.01)
+	assert.Nil(t, data.Battery.TimeToEmpty)
+	assert.Empty(t, model.history["123"])
+}
+
+func TestBatteryModel_IgnoresEnvironmentVoltage(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{Enabled: true}, domain.NodeIDFormatDecimal)
+
+	voltage := 12.1
+	data := &domain.TelemetryData{NodeID: "123", Type: domain.TelemetryTypeEnvironment, Voltage: &voltage}
+	model.apply(data, time.Now())
+
+	assert.Nil(t, data.Battery)
+	assert.Empty(t, model.history["123"])
+}
+
+func TestBatteryModel_NodeChemistry(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{
+		Enabled:       true,
+		NodeChemistry: map[string]string{"4187143508": domain.BatteryChemistryLiFePO4, "!00000001": "agm"},
+		Curves:        map[string]domain.BatteryCurve{"agm": {11.8, 12.7}},
+	}, domain.NodeIDFormatHex)
+
+	lifepo4 := batteryTelemetry("!f992bd54", 3.27, 50)
+	model.apply(lifepo4, time.Now())
+	liion := batteryTelemetry("!00000002", 3.27, 50)
+	model.apply(liion, time.Now())
+	agm := batteryTelemetry("!00000001", 12.25, 50)
+	model.apply(agm, time.Now())
+
+	assert.InDelta(t, 60, lifepo4.Battery.StateOfCharge, 0.01)
+	assert.InDelta(t, 8.5, liion.Battery.StateOfCharge, 0.01)
+	assert.InDelta(t, 50, agm.Battery.StateOfCharge, 0.01)
+}
+
+func TestBatteryModel_Disabled(t *testing.T) {
+	t.Parallel()
+	model := newBatteryModel(domain.BatteryModelConfig{}, domain.NodeIDFormatDecimal)
+	assert.Nil(t, model)
+
+	data := batteryTelemetry("123", 3.9, 70)
+	model.apply(data, time.Now())
+	assert.Nil(t, data.Battery)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_BatteryModel(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		Battery: domain.BatteryModelConfig{Enabled: true, Chemistry: domain.BatteryChemistryLiFePO4},
+	})
+
+	payload := []byte(`{"from": 123456789, "type": "telemetry", "payload": {"battery_level": 101, "voltage": 3.4}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, mockCollector.TelemetryData, 1)
+	battery := mockCollector.TelemetryData[0].Battery
+	require.NotNil(t, battery)
+	assert.True(t, battery.ExternalPower)
+	assert.InDelta(t, 100, battery.StateOfCharge, 0.01)
+}
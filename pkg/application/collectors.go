@@ -0,0 +1,65 @@
+package application
+
+import (
+	"meshtastic-exporter/pkg/domain"
+)
+
+// packetStatsFor статистика приёма, если коллектор её поддерживает, иначе пустышка.
+func packetStatsFor(collector domain.MetricsCollector) domain.PacketStatsCollector {
+	if stats, ok := collector.(domain.PacketStatsCollector); ok {
+		return stats
+	}
+	return noPacketStats{}
+}
+
+// modulesFor метрики модулей или счётчик сообщений для коллекторов без ModuleCollector.
+func modulesFor(collector domain.MetricsCollector) domain.ModuleCollector {
+	if modules, ok := collector.(domain.ModuleCollector); ok {
+		return modules
+	}
+	return countingModules{collector: collector}
+}
+
+type noPacketStats struct{}
+
+func (noPacketStats) UpdateUndecryptableCounter(string)    {}
+func (noPacketStats) CollectReception(domain.Reception)    {}
+func (noPacketStats) UpdateDuplicateCounter(string)        {}
+func (noPacketStats) CollectPacketHops(string, int)        {}
+func (noPacketStats) UpdateRelayCounter(string)            {}
+func (noPacketStats) CollectDelivery(domain.DeliveryStats) {}
+
+// countingModules только считает сообщения модулей в meshtastic_messages_total.
+type countingModules struct {
+	collector domain.MetricsCollector
+}
+
+func (m countingModules) CollectMapReport(report domain.MapReport) error {
+	m.collector.UpdateMessageCounter(report.NodeID, domain.MessageTypeMapReport)
+	return nil
+}
+
+func (m countingModules) CollectTraceroute(tr domain.Traceroute) error {
+	m.collector.UpdateMessageCounter(tr.Destination, domain.MessageTypeTraceroute)
+	return nil
+}
+
+func (m countingModules) CollectRoutingResult(result domain.RoutingResult) error {
+	m.collector.UpdateMessageCounter(result.NodeID, domain.MessageTypeRouting)
+	return nil
+}
+
+func (m countingModules) CollectDetection(event domain.DetectionEvent) error {
+	m.collector.UpdateMessageCounter(event.NodeID, domain.MessageTypeDetection)
+	return nil
+}
+
+func (m countingModules) CollectPaxcount(pax domain.Paxcount) error {
+	m.collector.UpdateMessageCounter(pax.NodeID, domain.MessageTypePaxcounter)
+	return nil
+}
+
+func (m countingModules) CollectRangeTest(packet domain.RangeTestPacket) error {
+	m.collector.UpdateMessageCounter(packet.NodeID, domain.MessageTypeRangeTest)
+	return nil
+}
@@ -0,0 +1,50 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/meshpb"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+// legacyCollector реализует только domain.MetricsCollector, как коллекторы
+// встраивающих приложений, написанные до опциональных интерфейсов.
+type legacyCollector struct {
+	domain.MetricsCollector
+	counted []string
+}
+
+func (c *legacyCollector) UpdateMessageCounter(nodeID string, messageType string) {
+	c.counted = append(c.counted, messageType)
+}
+
+func TestMeshtasticProcessor_LegacyCollector(t *testing.T) {
+	t.Parallel()
+	mock := &mocks.MockMetricsCollector{}
+	collector := &legacyCollector{MetricsCollector: mock}
+	processor := newProtobufProcessor(collector)
+
+	payload := routeDiscovery([]uint32{0x0b}, []int64{25}, nil, nil)
+	envelope := meshpb.ServiceEnvelope{
+		Packet: &meshpb.MeshPacket{
+			From:    0x0c,
+			To:      0x0a,
+			ID:      7,
+			RxRSSI:  -90,
+			Decoded: &meshpb.Data{PortNum: meshpb.PortNumTraceroute, Payload: payload, RequestID: 6},
+		},
+		GatewayID: "!0000000a",
+	}
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelope.Marshal())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{domain.MessageTypeTraceroute}, collector.counted)
+	assert.Empty(t, mock.Traceroutes)
+	assert.Empty(t, mock.Receptions)
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupKey пакет однозначно определяется отправителем и его packet id.
+type dedupKey struct {
+	from uint32
+	id   uint32
+}
+
+// dedupCache помнит пакеты, принятые в течение окна, чтобы копии от разных шлюзов
+// обрабатывались один раз.
+type dedupCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[dedupKey]time.Time
+	lastPrune time.Time
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	if window <= 0 {
+		return nil
+	}
+	return &dedupCache{
+		window: window,
+		seen:   make(map[dedupKey]time.Time),
+	}
+}
+
+// isDuplicate отмечает пакет как принятый и возвращает true, если он уже был в окне.
+// Пакеты без id не дедуплицируются.
+func (d *dedupCache) isDuplicate(from, id uint32, now time.Time) bool {
+	if d == nil || id == 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastPrune) > d.window {
+		d.prune(now)
+	}
+
+	key := dedupKey{from: from, id: id}
+	if firstSeen, exists := d.seen[key]; exists && now.Sub(firstSeen) <= d.window {
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+func (d *dedupCache) prune(now time.Time) {
+	for key, firstSeen := range d.seen {
+		if now.Sub(firstSeen) > d.window {
+			delete(d.seen, key)
+		}
+	}
+	d.lastPrune = now
+}
+
+func (d *dedupCache) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.seen)
+}
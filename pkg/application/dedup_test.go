@@ -0,0 +1,80 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+func TestDedupCache_IsDuplicate(t *testing.T) {
+	t.Parallel()
+	cache := newDedupCache(time.Minute)
+	now := time.Now()
+
+	assert.False(t, cache.isDuplicate(123, 1, now))
+	assert.True(t, cache.isDuplicate(123, 1, now.Add(10*time.Second)))
+	assert.False(t, cache.isDuplicate(456, 1, now), "same id from another node")
+	assert.False(t, cache.isDuplicate(123, 2, now))
+	assert.False(t, cache.isDuplicate(123, 0, now))
+	assert.False(t, cache.isDuplicate(123, 0, now), "packets without id are never duplicates")
+
+	// окно считается от первого приёма
+	assert.False(t, cache.isDuplicate(123, 1, now.Add(2*time.Minute)))
+}
+
+func TestDedupCache_Prune(t *testing.T) {
+	t.Parallel()
+	cache := newDedupCache(time.Minute)
+	now := time.Now()
+
+	for id := uint32(1); id <= 100; id++ {
+		cache.isDuplicate(123, id, now)
+	}
+	assert.Equal(t, 100, cache.len())
+
+	cache.isDuplicate(123, 1000, now.Add(2*time.Minute))
+	assert.Equal(t, 1, cache.len())
+}
+
+func TestDedupCache_Disabled(t *testing.T) {
+	t.Parallel()
+	cache := newDedupCache(0)
+	require.Nil(t, cache)
+
+	assert.False(t, cache.isDuplicate(123, 1, time.Now()))
+	assert.False(t, cache.isDuplicate(123, 1, time.Now()))
+}
+
+func TestMeshtasticProcessor_DuplicateFromAnotherGateway(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		DedupWindow: domain.DefaultDedupWindow,
+	})
+
+	packet := `{"from": 123456789, "id": 42, "sender": "%s", "type": "telemetry", "rssi": %d, "payload": {"battery_level": 85}}`
+	for _, dup := range []struct {
+		gateway string
+		rssi    int
+	}{{"!aaaa0001", -80}, {"!aaaa0002", -110}, {"!aaaa0003", -95}} {
+		payload := []byte(fmt.Sprintf(packet, dup.gateway, dup.rssi))
+		require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+	}
+
+	assert.Len(t, mockCollector.TelemetryData, 1)
+	require.Len(t, mockCollector.Receptions, 3)
+	assert.Equal(t, -110.0, *mockCollector.Receptions[1].RSSI)
+	assert.Equal(t, []string{"!aaaa0002", "!aaaa0003"}, mockCollector.DuplicateGateways)
+
+	// следующий пакет той же ноды обрабатывается
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", []byte(fmt.Sprintf(
+		`{"from": 123456789, "id": 43, "sender": "%s", "type": "telemetry", "rssi": %d, "payload": {"battery_level": 84}}`, "!aaaa0001", -80))))
+	assert.Len(t, mockCollector.TelemetryData, 2)
+}
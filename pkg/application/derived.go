@@ -0,0 +1,87 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+// derivedMetrics вычисляет domain.DerivedMetrics из телеметрии окружения.
+// Высоту для приведения давления к уровню моря берёт из последней позиции ноды,
+// пока позиция не старше TTL, как и её метрики.
+type derivedMetrics struct {
+	mu        sync.RWMutex
+	ttl       time.Duration
+	altitudes map[string]altitudeSample // nodeID -> последняя высота
+	lastPrune time.Time
+}
+
+type altitudeSample struct {
+	meters float64
+	at     time.Time
+}
+
+func newDerivedMetrics(enabled bool, ttl time.Duration) *derivedMetrics {
+	if !enabled {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = domain.DefaultMetricsTTL
+	}
+	return &derivedMetrics{ttl: ttl, altitudes: make(map[string]altitudeSample)}
+}
+
+func (d *derivedMetrics) observePosition(pos domain.Position) {
+	if d == nil || pos.Altitude == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune(pos.Timestamp)
+	d.altitudes[pos.NodeID] = altitudeSample{meters: float64(*pos.Altitude), at: pos.Timestamp}
+}
+
+// prune удаляет высоты старше TTL, не чаще раза в TTL.
+func (d *derivedMetrics) prune(now time.Time) {
+	if now.Sub(d.lastPrune) <= d.ttl {
+		return
+	}
+	d.lastPrune = now
+	for nodeID, altitude := range d.altitudes {
+		if now.Sub(altitude.at) > d.ttl {
+			delete(d.altitudes, nodeID)
+		}
+	}
+}
+
+// apply дописывает производные метрики в data.Sensors.
+func (d *derivedMetrics) apply(data *domain.TelemetryData) {
+	if d == nil {
+		return
+	}
+
+	values := make(map[string]float64)
+	if data.Temperature != nil && data.RelativeHumidity != nil && *data.RelativeHumidity > 0 {
+		values[domain.MetricDewPoint] = domain.DewPoint(*data.Temperature, *data.RelativeHumidity)
+		values[domain.MetricHeatIndex] = domain.HeatIndex(*data.Temperature, *data.RelativeHumidity)
+		values[domain.MetricAbsoluteHumidity] = domain.AbsoluteHumidity(*data.Temperature, *data.RelativeHumidity)
+	}
+	if altitude, ok := d.altitude(data.NodeID, data.Timestamp); ok && data.BarometricPressure != nil && *data.BarometricPressure > 0 {
+		values[domain.MetricSeaLevelPressure] = domain.SeaLevelPressure(*data.BarometricPressure, altitude, data.Temperature)
+	}
+
+	for metricName, value := range values {
+		if data.Sensors == nil {
+			data.Sensors = make(map[string]float64)
+		}
+		data.Sensors[metricName] = roundToTwoDecimals(value)
+	}
+}
+
+func (d *derivedMetrics) altitude(nodeID string, now time.Time) (float64, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	altitude, ok := d.altitudes[nodeID]
+	return altitude.meters, ok && now.Sub(altitude.at) <= d.ttl
+}
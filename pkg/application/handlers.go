@@ -0,0 +1,108 @@
+package application
+
+import (
+	"fmt"
+	"sync"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/errors"
+	"meshtastic-exporter/pkg/meshpb"
+)
+
+// Handler обработчик сообщений, зарегистрированный встраивающим приложением.
+// Метрики пишет через collector: встроенные Collect*, UpdateMessageCounter
+// или свои коллекторы в collector.GetRegistry(). Сообщения не считаются
+// в meshtastic_messages_total, пока обработчик не вызовет UpdateMessageCounter.
+type Handler func(collector domain.MetricsCollector, nodeID string, msg domain.MeshtasticMessage) error
+
+// HandlerRegistry обработчики по типу сообщения и portnum. Нужен для приложений,
+// которых нет во встроенной обработке, например PRIVATE_APP, без форка экспортера.
+// Безопасен для конкурентного использования.
+type HandlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	ports    map[meshpb.PortNum]string
+}
+
+// DefaultHandlers начальный реестр процессоров, у которых не задан ProcessorOptions.Handlers.
+// Процессор копирует его при создании: регистрации после этого на процессор не влияют.
+var DefaultHandlers = NewHandlerRegistry()
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[string]Handler),
+		ports:    make(map[meshpb.PortNum]string),
+	}
+}
+
+// Register обработчик сообщений типа messageType. Для встроенного типа заменяет
+// встроенную обработку, повторная регистрация заменяет предыдущий обработчик.
+func (r *HandlerRegistry) Register(messageType string, handler Handler) error {
+	if messageType == "" {
+		return errors.NewValidationError("message type is required", nil)
+	}
+	if handler == nil {
+		return errors.NewValidationError(fmt.Sprintf("handler for %q is nil", messageType), nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[messageType] = handler
+	return nil
+}
+
+// RegisterPort обработчик protobuf пакетов с portnum port. Пакеты получают тип
+// messageType, payload не разбирается: обработчик декодирует msg.RawPayload сам.
+func (r *HandlerRegistry) RegisterPort(port meshpb.PortNum, messageType string, handler Handler) error {
+	if port == meshpb.PortNumUnknown || port > meshpb.PortNumMaxPortNumberApp {
+		return errors.NewValidationError(fmt.Sprintf("invalid portnum %d", port), nil)
+	}
+	if err := r.Register(messageType, handler); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ports[port] = messageType
+	return nil
+}
+
+// clone копия реестра для процессора, чтобы регистрации в исходном реестре
+// не меняли обработку в уже созданных процессорах.
+func (r *HandlerRegistry) clone() *HandlerRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := NewHandlerRegistry()
+	for messageType, handler := range r.handlers {
+		c.handlers[messageType] = handler
+	}
+	for port, messageType := range r.ports {
+		c.ports[port] = messageType
+	}
+	return c
+}
+
+func (r *HandlerRegistry) handler(messageType string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[messageType]
+	return handler, ok
+}
+
+func (r *HandlerRegistry) portMessageType(port meshpb.PortNum) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	messageType, ok := r.ports[port]
+	return messageType, ok
+}
+
+// RegisterHandler регистрирует обработчик типа сообщения в DefaultHandlers.
+func RegisterHandler(messageType string, handler Handler) error {
+	return DefaultHandlers.Register(messageType, handler)
+}
+
+// RegisterPortHandler регистрирует обработчик portnum в DefaultHandlers.
+func RegisterPortHandler(port meshpb.PortNum, messageType string, handler Handler) error {
+	return DefaultHandlers.RegisterPort(port, messageType, handler)
+}
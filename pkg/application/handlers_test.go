@@ -0,0 +1,153 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/meshpb"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+type handledMessage struct {
+	nodeID string
+	msg    domain.MeshtasticMessage
+}
+
+func recordingHandler(calls *[]handledMessage) Handler {
+	return func(collector domain.MetricsCollector, nodeID string, msg domain.MeshtasticMessage) error {
+		*calls = append(*calls, handledMessage{nodeID: nodeID, msg: msg})
+		return nil
+	}
+}
+
+func TestHandlerRegistry_PrivatePort(t *testing.T) {
+	t.Parallel()
+	var calls []handledMessage
+	handlers := NewHandlerRegistry()
+	require.NoError(t, handlers.RegisterPort(meshpb.PortNumPrivate, "private", recordingHandler(&calls)))
+
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		Handlers:        handlers,
+	})
+
+	raw := []byte{0x0a, 0x2c, 0x01}
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumPrivate, raw))
+
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	assert.Equal(t, "123456789", calls[0].nodeID)
+	assert.Equal(t, "private", calls[0].msg.Type)
+	assert.Equal(t, raw, calls[0].msg.RawPayload)
+	assert.Nil(t, calls[0].msg.Payload)
+	assert.Equal(t, "!f992bd54", calls[0].msg.GatewayID)
+	assert.Equal(t, -90.0, *calls[0].msg.RSSI)
+}
+
+func TestHandlerRegistry_JSONRawPayload(t *testing.T) {
+	t.Parallel()
+	var calls []handledMessage
+	handlers := NewHandlerRegistry()
+	require.NoError(t, handlers.Register("soil", recordingHandler(&calls)))
+
+	processor := NewMeshtasticProcessorWithOptions(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, ProcessorOptions{Handlers: handlers})
+	payload := []byte(`{"from": 123456789, "type": "soil", "payload": {"moisture": [31, 28]}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, calls, 1)
+	assert.JSONEq(t, `{"moisture": [31, 28]}`, string(calls[0].msg.RawPayload))
+	assert.Equal(t, []interface{}{31.0, 28.0}, calls[0].msg.Payload["moisture"])
+}
+
+func TestHandlerRegistry_OverridesBuiltinType(t *testing.T) {
+	t.Parallel()
+	var calls []handledMessage
+	handlers := NewHandlerRegistry()
+	require.NoError(t, handlers.Register(domain.MessageTypePosition, recordingHandler(&calls)))
+
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		Handlers: handlers,
+	})
+
+	err := processor.ProcessMessage(context.Background(), "msh/test", []byte(`{
+		"from": 123456789,
+		"type": "position",
+		"payload": {"latitude_i": 557558000, "longitude_i": 376173000}
+	}`))
+
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	assert.Equal(t, 557558000.0, calls[0].msg.Payload["latitude_i"])
+	assert.Empty(t, collector.PositionData)
+}
+
+func TestHandlerRegistry_HandlerError(t *testing.T) {
+	t.Parallel()
+	handlerErr := errors.New("bad payload")
+	handlers := NewHandlerRegistry()
+	require.NoError(t, handlers.RegisterPort(meshpb.PortNumPrivate, "private",
+		func(domain.MetricsCollector, string, domain.MeshtasticMessage) error { return handlerErr }))
+
+	processor := NewMeshtasticProcessorWithOptions(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		Handlers:        handlers,
+	})
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumPrivate, []byte{1}))
+
+	assert.ErrorIs(t, err, handlerErr)
+}
+
+func TestHandlerRegistry_UnregisteredPortUnsupported(t *testing.T) {
+	t.Parallel()
+	var calls []handledMessage
+	handlers := NewHandlerRegistry()
+	require.NoError(t, handlers.RegisterPort(meshpb.PortNumPrivate, "private", recordingHandler(&calls)))
+
+	processor := NewMeshtasticProcessorWithOptions(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		Handlers:        handlers,
+	})
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumPrivate+1, []byte{1}))
+
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+}
+
+func TestHandlerRegistry_Validation(t *testing.T) {
+	t.Parallel()
+	handlers := NewHandlerRegistry()
+	noop := func(domain.MetricsCollector, string, domain.MeshtasticMessage) error { return nil }
+
+	assert.Error(t, handlers.Register("", noop))
+	assert.Error(t, handlers.Register("private", nil))
+	assert.Error(t, handlers.RegisterPort(meshpb.PortNumUnknown, "private", noop))
+	assert.Error(t, handlers.RegisterPort(meshpb.PortNumMaxPortNumberApp+1, "private", noop))
+
+	_, registered := handlers.portMessageType(meshpb.PortNumPrivate)
+	assert.False(t, registered)
+}
+
+func TestHandlerRegistry_CopiedAtConstruction(t *testing.T) {
+	t.Parallel()
+	var calls []handledMessage
+	handlers := NewHandlerRegistry()
+	processor := NewMeshtasticProcessorWithOptions(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		Handlers:        handlers,
+	})
+	require.NoError(t, handlers.RegisterPort(meshpb.PortNumPrivate, "private", recordingHandler(&calls)))
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumPrivate, []byte{1}))
+
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+}
@@ -0,0 +1,136 @@
+package application
+
+import (
+	"strconv"
+	"strings"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/validator"
+)
+
+// metricMapper вычисляет метрики из hook.prometheus.mappings по payload сообщений.
+// nil — маппингов нет.
+type metricMapper struct {
+	byType map[string][]compiledMapping
+}
+
+type compiledMapping struct {
+	metric     string
+	path       []string
+	labelPaths [][]string // в порядке MetricMapping.LabelNames
+	scale      float64
+}
+
+func newMetricMapper(mappings []domain.MetricMapping) *metricMapper {
+	if len(mappings) == 0 {
+		return nil
+	}
+	m := &metricMapper{byType: make(map[string][]compiledMapping)}
+	for _, mapping := range mappings {
+		compiled := compiledMapping{
+			metric: mapping.MetricName(),
+			path:   splitPath(mapping.Path),
+			scale:  mapping.Scale,
+		}
+		if compiled.scale == 0 {
+			compiled.scale = 1
+		}
+		for _, name := range mapping.LabelNames() {
+			compiled.labelPaths = append(compiled.labelPaths, splitPath(mapping.Labels[name]))
+		}
+		m.byType[mapping.MessageType] = append(m.byType[mapping.MessageType], compiled)
+	}
+	return m
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// evaluate значения маппингов для типа сообщения. Маппинг, поля которого нет
+// в payload или оно не число, пропускается.
+func (m *metricMapper) evaluate(nodeID string, msg domain.MeshtasticMessage) []domain.MappedValue {
+	if m == nil {
+		return nil
+	}
+	var values []domain.MappedValue
+	for _, mapping := range m.byType[msg.Type] {
+		value, ok := mapping.value(msg.Payload)
+		if !ok {
+			continue
+		}
+		values = append(values, domain.MappedValue{
+			Metric: mapping.metric,
+			NodeID: nodeID,
+			Labels: mapping.labels(msg.Payload),
+			Value:  value * mapping.scale,
+		})
+	}
+	return values
+}
+
+// value значение поля, counter без пути считает сообщения.
+func (c compiledMapping) value(payload map[string]interface{}) (float64, bool) {
+	if c.path == nil {
+		return 1, true
+	}
+	switch val := lookupPath(payload, c.path).(type) {
+	case float64:
+		return val, true
+	case bool:
+		return boolToFloat(val), true
+	}
+	return 0, false
+}
+
+func (c compiledMapping) labels(payload map[string]interface{}) []string {
+	labels := make([]string, len(c.labelPaths))
+	for i, path := range c.labelPaths {
+		labels[i] = labelValue(lookupPath(payload, path))
+	}
+	return labels
+}
+
+func labelValue(value interface{}) string {
+	switch val := value.(type) {
+	case string:
+		if sanitized := validator.SanitizeString(val); sanitized != "" {
+			return sanitized
+		}
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return unknownValue
+}
+
+// lookupPath значение по пути: ключи вложенных объектов и индексы массивов.
+func lookupPath(payload map[string]interface{}, path []string) interface{} {
+	var current interface{} = payload
+	for _, key := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			current = node[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+func TestMetricMapper_Evaluate(t *testing.T) {
+	t.Parallel()
+	mapper := newMetricMapper([]domain.MetricMapping{
+		{MessageType: "telemetry", Path: "soil.conductivity", Metric: "meshtastic_soil_conductivity", Scale: 0.1,
+			Labels: map[string]string{"sensor": "soil.model", "probe": "soil.probe"}},
+		{MessageType: "telemetry", Path: "heater_on", Metric: "meshtastic_heater_on"},
+		{MessageType: "telemetry", Path: "probes.1.depth", Metric: "meshtastic_probe_depth"},
+		{MessageType: "telemetry", Path: "missing", Metric: "meshtastic_missing"},
+		{MessageType: "text", Metric: "meshtastic_texts", Type: domain.MappingTypeCounter},
+	})
+
+	msg := domain.MeshtasticMessage{
+		Type: domain.MessageTypeTelemetry,
+		Payload: map[string]interface{}{
+			"soil":      map[string]interface{}{"conductivity": 1234.0, "model": "THC-S", "probe": 2.0},
+			"heater_on": true,
+			"probes":    []interface{}{map[string]interface{}{"depth": 10.0}, map[string]interface{}{"depth": 30.0}},
+		},
+	}
+	values := mapper.evaluate("123", msg)
+
+	require.Len(t, values, 3)
+	assert.Equal(t, "meshtastic_soil_conductivity", values[0].Metric)
+	assert.Equal(t, "123", values[0].NodeID)
+	assert.Equal(t, []string{"2", "THC-S"}, values[0].Labels) // probe, sensor
+	assert.InDelta(t, 123.4, values[0].Value, 0.001)
+	assert.InDelta(t, 1, values[1].Value, 0.001)
+	assert.InDelta(t, 30, values[2].Value, 0.001)
+
+	text := mapper.evaluate("123", domain.MeshtasticMessage{Type: domain.MessageTypeText})
+	require.Len(t, text, 1)
+	assert.Equal(t, "meshtastic_texts_total", text[0].Metric)
+	assert.InDelta(t, 1, text[0].Value, 0.001)
+}
+
+func TestMetricMapper_Disabled(t *testing.T) {
+	t.Parallel()
+	mapper := newMetricMapper(nil)
+
+	assert.Nil(t, mapper)
+	assert.Nil(t, mapper.evaluate("123", domain.MeshtasticMessage{Type: domain.MessageTypeTelemetry}))
+}
+
+func TestMeshtasticProcessor_ProcessMessage_Mappings(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		Mappings: []domain.MetricMapping{
+			{MessageType: "telemetry", Path: "soil_conductivity", Metric: "meshtastic_soil_conductivity", Unit: "microsiemens"},
+		},
+	})
+
+	payload := []byte(`{"from": 123456789, "type": "telemetry", "payload": {"soil_conductivity": 850, "battery_level": 80}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, mockCollector.MappedValues, 1)
+	assert.Equal(t, domain.MappedValue{
+		Metric: "meshtastic_soil_conductivity_microsiemens",
+		NodeID: "123456789",
+		Labels: []string{},
+		Value:  850,
+	}, mockCollector.MappedValues[0])
+	// встроенная обработка телеметрии не меняется
+	require.Len(t, mockCollector.TelemetryData, 1)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_SkippedMappings(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{SkippedMappings: []string{"meshtastic_probe_depth"}}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		Mappings: []domain.MetricMapping{
+			{MessageType: "telemetry", Path: "soil_conductivity", Metric: "meshtastic_soil_conductivity"},
+			{MessageType: "telemetry", Path: "probe.depth", Metric: "meshtastic_probe_depth", Labels: map[string]string{"probe": "probe.id"}},
+		},
+	})
+
+	payload := []byte(`{"from": 123456789, "type": "telemetry", "payload": {"soil_conductivity": 850, "probe": {"id": "a", "depth": 30}}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	// маппинг, который коллектор пропустил, не вычисляется
+	require.Len(t, mockCollector.MappedValues, 1)
+	assert.Equal(t, "meshtastic_soil_conductivity", mockCollector.MappedValues[0].Metric)
+}
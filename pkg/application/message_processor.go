@@ -1,8 +1,10 @@
 package application
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	"meshtastic-exporter/pkg/domain"
 	"meshtastic-exporter/pkg/errors"
 	"meshtastic-exporter/pkg/logger"
+	"meshtastic-exporter/pkg/meshpb"
 	"meshtastic-exporter/pkg/validator"
 )
 
@@ -23,25 +26,124 @@ const (
 	deviceMetricsType      = "device_metrics"
 )
 
+type ProcessorOptions struct {
+	LogAllMessages bool
+	TopicPattern   string
+	// ProtobufPattern топики с ServiceEnvelope (msh/+/+/e/#), пустой паттерн отключает protobuf.
+	ProtobufPattern string
+	// MapReportPattern топики с MAP_REPORT_APP (msh/+/2/map/#), пустой паттерн отключает map reports.
+	MapReportPattern string
+	// TopicTemplate шаблон для извлечения региона, канала и шлюза из топика, пустой — не разбирать.
+	TopicTemplate string
+	// ChannelKeys имя канала -> base64 PSK для расшифровки MeshPacket.encrypted
+	ChannelKeys map[string]string
+	// DedupWindow окно, в котором копии пакета от разных шлюзов считаются дублями, 0 — не дедуплицировать.
+	DedupWindow time.Duration
+	// NodeIDFormat формат лейбла node_id: decimal (по умолчанию), hex или both.
+	NodeIDFormat string
+	// DerivedMetrics включает вычисление точки росы, heat index, абсолютной влажности и давления на уровне моря.
+	DerivedMetrics bool
+	// Battery модель батареи: заряд по кривой напряжения и прогноз времени до разряда.
+	Battery domain.BatteryModelConfig
+	// PacketLoss оценка потерь пакетов по интервалам рассылки нод.
+	PacketLoss domain.PacketLossConfig
+	// MetricsTTL через сколько забывать состояние молчащей ноды, 0 — domain.DefaultMetricsTTL.
+	MetricsTTL time.Duration
+	// Mappings метрики из полей payload, объявленные в конфигурации.
+	Mappings []domain.MetricMapping
+	// Handlers обработчики встраивающего приложения, nil — DefaultHandlers. Копируется при создании процессора.
+	Handlers *HandlerRegistry
+}
+
 type MeshtasticProcessor struct {
-	collector      domain.MetricsCollector
-	alerter        domain.AlertSender
-	logger         zerolog.Logger
-	logAllMessages bool
-	topicPattern   string
+	collector       domain.MetricsCollector
+	packets         domain.PacketStatsCollector
+	modules         domain.ModuleCollector
+	mappedValues    domain.MappedValuesCollector
+	alerter         domain.AlertSender
+	logger          zerolog.Logger
+	logAllMessages  bool
+	topicPattern    string
+	protobufPattern string
+	mapPattern      string
+	topicTemplate   *validator.TopicTemplate
+	dedup           *dedupCache
+	nodeIDFormat    string
+	derived         *derivedMetrics
+	battery         *batteryModel
+	delivery        *deliveryTracker
+	mapper          *metricMapper
+	handlers        *HandlerRegistry
+	keyring         *meshpb.Keyring
 }
 
 func NewMeshtasticProcessor(collector domain.MetricsCollector, alerter domain.AlertSender, logAllMessages bool, topicPattern string) *MeshtasticProcessor {
-	return &MeshtasticProcessor{
-		collector:      collector,
-		alerter:        alerter,
-		logger:         logger.ComponentLogger("message-processor"),
-		logAllMessages: logAllMessages,
-		topicPattern:   topicPattern,
+	return NewMeshtasticProcessorWithOptions(collector, alerter, ProcessorOptions{
+		LogAllMessages: logAllMessages,
+		TopicPattern:   topicPattern,
+	})
+}
+
+func NewMeshtasticProcessorWithOptions(collector domain.MetricsCollector, alerter domain.AlertSender, opts ProcessorOptions) *MeshtasticProcessor {
+	p := &MeshtasticProcessor{
+		collector:       collector,
+		packets:         packetStatsFor(collector),
+		modules:         modulesFor(collector),
+		alerter:         alerter,
+		logger:          logger.ComponentLogger("message-processor"),
+		logAllMessages:  opts.LogAllMessages,
+		topicPattern:    opts.TopicPattern,
+		protobufPattern: opts.ProtobufPattern,
+		mapPattern:      opts.MapReportPattern,
+		dedup:           newDedupCache(opts.DedupWindow),
+		nodeIDFormat:    opts.NodeIDFormat,
+		derived:         newDerivedMetrics(opts.DerivedMetrics, opts.MetricsTTL),
+		battery:         newBatteryModel(opts.Battery, opts.NodeIDFormat),
+		delivery:        newDeliveryTracker(opts.PacketLoss, opts.MetricsTTL),
+	}
+
+	// без MappedValuesCollector маппинги не вычисляются, пропущенные коллектором тоже
+	if mappedValues, ok := collector.(domain.MappedValuesCollector); ok {
+		p.mappedValues = mappedValues
+		p.mapper = newMetricMapper(registeredMappings(opts.Mappings, mappedValues))
+	}
+
+	handlers := opts.Handlers
+	if handlers == nil {
+		handlers = DefaultHandlers
+	}
+	p.handlers = handlers.clone()
+
+	keyring, err := meshpb.NewKeyring(opts.ChannelKeys)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("invalid channel keys skipped")
+	}
+	p.keyring = keyring
+
+	topicTemplate, err := validator.ParseTopicTemplate(opts.TopicTemplate)
+	if err != nil {
+		p.logger.Warn().Err(err).Str("template", opts.TopicTemplate).Msg("invalid topic template ignored")
+	}
+	p.topicTemplate = topicTemplate
+
+	return p
+}
+
+func registeredMappings(mappings []domain.MetricMapping, collector domain.MappedValuesCollector) []domain.MetricMapping {
+	var registered []domain.MetricMapping
+	for _, mapping := range mappings {
+		if collector.MappingRegistered(mapping) {
+			registered = append(registered, mapping)
+		}
 	}
+	return registered
 }
 
 func (p *MeshtasticProcessor) ProcessMessage(ctx context.Context, topic string, payload []byte) error {
+	if p.isProtobufTopic(topic) {
+		return p.processProtobufMessage(topic, payload)
+	}
+
 	p.logMessageIfEnabled(topic, payload)
 
 	if err := p.validateInput(topic, payload); err != nil {
@@ -53,12 +155,18 @@ func (p *MeshtasticProcessor) ProcessMessage(ctx context.Context, topic string,
 		return err
 	}
 
+	// ошибки разбора те же, что раньше давала проверка ValidateMeshtasticMessage
 	msg, err := p.parseMessage(payload)
 	if err != nil {
-		p.logger.Error().Err(err).Str("topic", topic).Msg("failed to parse message")
+		p.logger.Warn().Err(err).Str("topic", topic).Msg("validation failed")
 		return err
 	}
+	p.applyTopicInfo(&msg, topic)
+
+	return p.handleMessage(msg)
+}
 
+func (p *MeshtasticProcessor) handleMessage(msg domain.MeshtasticMessage) error {
 	nodeID, err := p.validateAndFormatNodeID(msg.From)
 	if err != nil {
 		p.logger.Warn().Err(err).Uint32("from", msg.From).Msg("invalid node ID")
@@ -66,11 +174,139 @@ func (p *MeshtasticProcessor) ProcessMessage(ctx context.Context, topic string,
 	}
 
 	// Обновляем timestamp для любого сообщения от ноды
-	p.collector.UpdateNodeLastSeen(nodeID, time.Now())
+	now := time.Now()
+	msg.ReceivedAt = receivedAt(msg.RxTime, now)
+	p.collector.UpdateNodeLastSeen(nodeID, msg.ReceivedAt)
+	rssi, snr := receptionSignal(msg)
+	p.packets.CollectReception(domain.Reception{
+		NodeID:      nodeID,
+		GatewayID:   msg.GatewayID,
+		Region:      msg.Region,
+		Channel:     msg.Channel,
+		MessageType: msg.Type,
+		RSSI:        rssi,
+		SNR:         snr,
+		RxTime:      rxTime(msg.RxTime),
+	})
+
+	// Копию пакета от другого шлюза учитываем только в статистике приёма
+	if p.dedup.isDuplicate(msg.From, msg.ID, now) {
+		p.packets.UpdateDuplicateCounter(msg.GatewayID)
+		return nil
+	}
+
+	p.collectRouting(msg, nodeID)
+	p.trackDelivery(msg, nodeID)
+	if values := p.mapper.evaluate(nodeID, msg); len(values) > 0 {
+		p.mappedValues.CollectMappedValues(values)
+	}
 
 	return p.processMessageByType(msg, nodeID)
 }
 
+// trackDelivery учитывает пакет в оценке потерь, телеметрия разбивается по подтипам.
+// Интервал neighborinfo нода сообщает сама.
+func (p *MeshtasticProcessor) trackDelivery(msg domain.MeshtasticMessage, nodeID string) {
+	if p.delivery == nil {
+		return
+	}
+	stream := msg.Type
+	if stream == domain.MessageTypeTelemetry {
+		stream = p.determineTelemetryType(msg.Payload)
+	}
+	if msg.Type == domain.MessageTypeNeighborInfo {
+		secs, _ := msg.Payload["node_broadcast_interval_secs"].(float64)
+		p.delivery.advertise(nodeID, stream, time.Duration(secs)*time.Second)
+	}
+	if stats, ok := p.delivery.observe(nodeID, stream, msg.ID, msg.ReceivedAt); ok {
+		p.packets.CollectDelivery(stats)
+	}
+}
+
+// receptionSignal RSSI и SNR приёма из корня сообщения, у телеметрии в старом
+// формате они бывают только в payload.
+func receptionSignal(msg domain.MeshtasticMessage) (*float64, *float64) {
+	rssi, snr := msg.RSSI, msg.SNR
+	if msg.Type != domain.MessageTypeTelemetry {
+		return rssi, snr
+	}
+	if val, ok := msg.Payload["rssi"].(float64); ok && rssi == nil {
+		rssi = &val
+	}
+	if val, ok := msg.Payload["snr"].(float64); ok && snr == nil {
+		snr = &val
+	}
+	return rssi, snr
+}
+
+func rxTime(seconds uint32) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+// receivedAt время приёма по часам шлюза. Пакету из будущего или слишком старому
+// не верим: у шлюза без GPS и NTP часы могут быть сбиты.
+func receivedAt(seconds uint32, now time.Time) time.Time {
+	rx := rxTime(seconds)
+	if rx.IsZero() || rx.After(now) || now.Sub(rx) > domain.MaxRxTimeAge {
+		return now
+	}
+	return rx
+}
+
+// applyTopicInfo дополняет сообщение регионом, каналом и шлюзом из топика.
+// Значения из самого сообщения имеют приоритет над топиком.
+func (p *MeshtasticProcessor) applyTopicInfo(msg *domain.MeshtasticMessage, topic string) {
+	info, ok := p.topicTemplate.Parse(topic)
+	if !ok {
+		info.Gateway = gatewayFromTopic(topic)
+	}
+
+	msg.Region = info.Region
+	if msg.Channel == "" {
+		msg.Channel = info.Channel
+	}
+	if msg.GatewayID == "" {
+		msg.GatewayID = info.Gateway
+	}
+}
+
+// gatewayFromTopic ID шлюза из последнего сегмента топика вида msh/.../!f992bd54.
+func gatewayFromTopic(topic string) string {
+	segment := topic[strings.LastIndex(topic, "/")+1:]
+	if strings.HasPrefix(segment, "!") {
+		return segment
+	}
+	return ""
+}
+
+// collectRouting число хопов до ноды и ретранслятор пакета.
+func (p *MeshtasticProcessor) collectRouting(msg domain.MeshtasticMessage, nodeID string) {
+	if hops, ok := hopsAway(msg); ok {
+		p.packets.CollectPacketHops(nodeID, hops)
+	}
+	if msg.RelayNode != nil && *msg.RelayNode != 0 {
+		p.packets.UpdateRelayCounter(fmt.Sprintf("0x%02x", *msg.RelayNode&0xff))
+	}
+}
+
+// hopsAway hops_away из JSON прошивки или hop_start - hop_limit.
+// Пакеты старых прошивок без hop_start не учитываются.
+func hopsAway(msg domain.MeshtasticMessage) (int, bool) {
+	if msg.HopsAway != nil && *msg.HopsAway <= domain.MaxHopLimit {
+		return int(*msg.HopsAway), true
+	}
+	if msg.HopStart == nil || msg.HopLimit == nil || *msg.HopStart == 0 {
+		return 0, false
+	}
+	if *msg.HopStart < *msg.HopLimit || *msg.HopStart > domain.MaxHopLimit {
+		return 0, false
+	}
+	return int(*msg.HopStart - *msg.HopLimit), true
+}
+
 func (p *MeshtasticProcessor) logMessageIfEnabled(topic string, payload []byte) {
 	if p.logAllMessages && validator.MatchesMQTTPattern(topic, p.topicPattern) {
 		//Int("payload_size", len(payload)).
@@ -84,7 +320,7 @@ func (p *MeshtasticProcessor) validateInput(topic string, payload []byte) error
 		return errors.NewValidationError("invalid topic", err)
 	}
 
-	if err := validator.ValidateMeshtasticMessage(payload); err != nil {
+	if err := validator.ValidateMessageFormat(payload); err != nil {
 		if strings.Contains(err.Error(), "not JSON format") {
 			return errors.NewValidationError("not JSON", err)
 		}
@@ -95,45 +331,141 @@ func (p *MeshtasticProcessor) validateInput(topic string, payload []byte) error
 	return nil
 }
 
-func (p *MeshtasticProcessor) parseMessage(payload []byte) (domain.MeshtasticMessage, error) {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(payload, &raw); err != nil {
-		return domain.MeshtasticMessage{}, errors.NewProcessingError("json parsing failed", err)
+// jsonMessage конверт JSON прошивки. Поля конверта разбираются сразу в типы за
+// один проход, payload — в map[string]interface{}: с ним работают обработчики
+// и маппинги, поэтому разбор не обходится без аллокаций.
+type jsonMessage struct {
+	From      jsonNumber  `json:"from"`
+	To        jsonNumber  `json:"to"`
+	ID        jsonNumber  `json:"id"`
+	Type      jsonString  `json:"type"`
+	Sender    jsonString  `json:"sender"`
+	Payload   jsonPayload `json:"payload"`
+	RSSI      jsonNumber  `json:"rssi"`
+	SNR       jsonNumber  `json:"snr"`
+	HopStart  jsonNumber  `json:"hop_start"`
+	HopLimit  jsonNumber  `json:"hop_limit"`
+	HopsAway  jsonNumber  `json:"hops_away"`
+	RelayNode jsonNumber  `json:"relay_node"`
+	Timestamp jsonNumber  `json:"timestamp"`
+}
+
+// jsonNumber число конверта. Значение другого типа не ошибка разбора: как и
+// прежде с map, такое поле считается отсутствующим.
+type jsonNumber struct {
+	value   float64
+	ok      bool
+	present bool
+}
+
+func (n *jsonNumber) UnmarshalJSON(b []byte) error {
+	n.present = true
+	value, err := strconv.ParseFloat(string(b), 64)
+	n.value, n.ok = value, err == nil
+	return nil
+}
+
+// uint32 число в диапазоне uint32 или nil, как getUint32.
+func (n jsonNumber) uint32() *uint32 {
+	if !n.ok || n.value < 0 || n.value > math.MaxUint32 {
+		return nil
 	}
+	result := uint32(n.value)
+	return &result
+}
 
-	msg := domain.MeshtasticMessage{
-		Type: p.getString(raw, "type"),
+func (n jsonNumber) float() *float64 {
+	if !n.ok {
+		return nil
 	}
+	value := n.value
+	return &value
+}
 
-	if from, ok := raw["from"].(float64); ok {
-		msg.From = uint32(from)
+// jsonPayload payload конверта: значение для обработчиков и исходные байты для RawPayload.
+type jsonPayload struct {
+	raw   []byte
+	value interface{}
+}
+
+// UnmarshalJSON не копирует b: как и у protobuf, RawPayload ссылается на буфер сообщения.
+func (p *jsonPayload) UnmarshalJSON(b []byte) error {
+	p.raw = b
+	return json.Unmarshal(b, &p.value)
+}
+
+// jsonString строка конверта, значение другого типа — пустая строка.
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(b []byte) error {
+	if len(b) < 2 || b[0] != '"' {
+		return nil
 	}
-	if rssi, ok := raw["rssi"].(float64); ok {
-		msg.RSSI = &rssi
+	if bytes.IndexByte(b, '\\') < 0 {
+		*s = jsonString(b[1 : len(b)-1])
+		return nil
 	}
-	if snr, ok := raw["snr"].(float64); ok {
-		msg.SNR = &snr
+	var value string
+	if json.Unmarshal(b, &value) == nil {
+		*s = jsonString(value)
 	}
+	return nil
+}
 
-	if msg.Type == "sendtext" {
-		if payloadStr, ok := raw["payload"].(string); ok {
-			msg.Payload = map[string]interface{}{"text": payloadStr}
-		}
-	} else {
-		if payloadObj, ok := raw["payload"].(map[string]interface{}); ok {
-			msg.Payload = payloadObj
-		}
+// parseMessage разбирает JSON сообщение один раз вместо проверки и разбора в map.
+func (p *MeshtasticProcessor) parseMessage(payload []byte) (domain.MeshtasticMessage, error) {
+	var raw jsonMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return domain.MeshtasticMessage{}, errors.NewValidationError("invalid message", fmt.Errorf("invalid JSON: %w", err))
+	}
+	if !raw.From.present {
+		return domain.MeshtasticMessage{}, errors.NewValidationError("invalid message", fmt.Errorf("missing 'from' field"))
 	}
 
+	msg := domain.MeshtasticMessage{
+		Type:      string(raw.Type),
+		GatewayID: string(raw.Sender),
+		RSSI:      raw.RSSI.float(),
+		SNR:       raw.SNR.float(),
+		HopStart:  raw.HopStart.uint32(),
+		HopLimit:  raw.HopLimit.uint32(),
+		HopsAway:  raw.HopsAway.uint32(),
+		RelayNode: raw.RelayNode.uint32(),
+	}
+	if raw.From.ok {
+		msg.From = uint32(raw.From.value)
+	}
+	if raw.ID.ok {
+		msg.ID = uint32(raw.ID.value)
+	}
+	if to := raw.To.uint32(); to != nil {
+		msg.To = *to
+	}
+	if rx := raw.Timestamp.uint32(); rx != nil {
+		msg.RxTime = *rx
+	}
+	msg.Payload = messagePayload(msg.Type, raw.Payload.value)
+	msg.RawPayload = raw.Payload.raw
 	return msg, nil
 }
 
+// messagePayload payload сообщения, у sendtext это строка с текстом.
+func messagePayload(msgType string, payload interface{}) map[string]interface{} {
+	if text, ok := payload.(string); ok && msgType == "sendtext" {
+		return map[string]interface{}{"text": text}
+	}
+	if object, ok := payload.(map[string]interface{}); ok && msgType != "sendtext" {
+		return object
+	}
+	return nil
+}
+
 func (p *MeshtasticProcessor) validateAndFormatNodeID(from uint32) (string, error) {
 	if from == 0 {
 		return "", errors.NewValidationError("empty sender", nil)
 	}
 
-	nodeID := strconv.FormatUint(uint64(from), 10)
+	nodeID := p.formatNodeID(from)
 	if err := validator.ValidateNodeID(nodeID); err != nil {
 		p.logger.Warn().Err(err).Str("node_id", nodeID).Msg("invalid node id")
 		return "", errors.NewValidationError("invalid node id", err)
@@ -142,34 +474,56 @@ func (p *MeshtasticProcessor) validateAndFormatNodeID(from uint32) (string, erro
 	return nodeID, nil
 }
 
-func (p *MeshtasticProcessor) processMessageByType(msg domain.MeshtasticMessage, nodeID string) error {
-	switch msg.Type {
-	case domain.MessageTypeTelemetry:
-		//p.logger.Debug().Str("node_id", nodeID).Msg("processing telemetry")
-		return p.processTelemetry(nodeID, msg)
-	case domain.MessageTypeNodeInfo:
-		return p.processNodeInfo(nodeID, msg.Payload)
-	case domain.MessageTypeText:
+func (p *MeshtasticProcessor) formatNodeID(nodeNum uint32) string {
+	return domain.FormatNodeID(nodeNum, p.nodeIDFormat)
+}
+
+// messageHandler обработчик сообщения одного типа.
+type messageHandler func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error
+
+var messageHandlers = map[string]messageHandler{
+	domain.MessageTypeTelemetry: (*MeshtasticProcessor).processTelemetry,
+	domain.MessageTypeNodeInfo: func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error {
+		return p.processNodeInfo(nodeID, msg.Payload, msg.ReceivedAt)
+	},
+	domain.MessageTypeText: func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error {
 		return p.processTextMessage(nodeID, msg.Payload)
-	case domain.MessageTypePosition:
-		return p.processPosition(nodeID, msg.Payload)
-	case domain.MessageTypeWaypoint:
+	},
+	domain.MessageTypePosition: func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error {
+		return p.processPosition(nodeID, msg.Payload, msg.ReceivedAt)
+	},
+	domain.MessageTypeWaypoint: func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error {
 		return p.processWaypoint(nodeID, msg.Payload)
-	case domain.MessageTypeNeighborInfo:
-		return p.processNeighborInfo(nodeID, msg.Payload)
-	default:
-		//p.logger.Debug().
-		//	Str("node_id", nodeID).
-		//	Msg("unsupported type")
-		p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeUnsupported)
-		return nil
+	},
+	domain.MessageTypeNeighborInfo: func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error {
+		return p.processNeighborInfo(nodeID, msg.Payload, msg.ReceivedAt)
+	},
+	domain.MessageTypeMapReport: func(p *MeshtasticProcessor, nodeID string, msg domain.MeshtasticMessage) error {
+		return p.processMapReport(nodeID, msg.Payload, msg.ReceivedAt)
+	},
+	domain.MessageTypeTraceroute: (*MeshtasticProcessor).processTraceroute,
+	domain.MessageTypeRouting:    (*MeshtasticProcessor).processRouting,
+	domain.MessageTypeDetection:  (*MeshtasticProcessor).processDetection,
+	domain.MessageTypePaxcounter: (*MeshtasticProcessor).processPaxcount,
+	domain.MessageTypeRangeTest:  (*MeshtasticProcessor).processRangeTest,
+}
+
+// processMessageByType обработчик из реестра встраивающего приложения имеет приоритет над встроенным.
+func (p *MeshtasticProcessor) processMessageByType(msg domain.MeshtasticMessage, nodeID string) error {
+	if handler, ok := p.handlers.handler(msg.Type); ok {
+		return handler(p.collector, nodeID, msg)
+	}
+	if handler, ok := messageHandlers[msg.Type]; ok {
+		return handler(p, nodeID, msg)
 	}
+	p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeUnsupported)
+	return nil
 }
 
 func (p *MeshtasticProcessor) processTelemetry(nodeID string, msg domain.MeshtasticMessage) error {
 	data := domain.TelemetryData{
 		NodeID:    nodeID,
-		Timestamp: time.Now(),
+		Timestamp: msg.ReceivedAt,
 	}
 
 	// Определяем тип телеметрии по наличию полей
@@ -177,6 +531,8 @@ func (p *MeshtasticProcessor) processTelemetry(nodeID string, msg domain.Meshtas
 
 	p.extractTelemetryFields(&data, msg.Payload)
 	p.extractTopLevelFields(&data, msg)
+	p.derived.apply(&data)
+	p.battery.apply(&data, data.Timestamp)
 	return p.collector.CollectTelemetry(data)
 }
 
@@ -211,6 +567,20 @@ func (p *MeshtasticProcessor) extractBasicFields(data *domain.TelemetryData, pay
 func (p *MeshtasticProcessor) extractEnvironmentalFields(data *domain.TelemetryData, payload map[string]interface{}) {
 	p.extractEnvironmentMetrics(data, payload)
 	p.extractPowerMetrics(data, payload)
+	p.extractSensorFields(data, payload)
+}
+
+func (p *MeshtasticProcessor) extractSensorFields(data *domain.TelemetryData, payload map[string]interface{}) {
+	for _, field := range domain.SensorFields {
+		val, ok := payload[field.Field].(float64)
+		if !ok {
+			continue
+		}
+		if data.Sensors == nil {
+			data.Sensors = make(map[string]float64)
+		}
+		data.Sensors[field.Metric] = truncateToTwoDecimals(val)
+	}
 }
 
 func (p *MeshtasticProcessor) extractEnvironmentMetrics(data *domain.TelemetryData, payload map[string]interface{}) {
@@ -280,14 +650,15 @@ func (p *MeshtasticProcessor) extractNetworkFields(data *domain.TelemetryData, p
 	}
 }
 
-func (p *MeshtasticProcessor) processNodeInfo(nodeID string, payload map[string]interface{}) error {
+func (p *MeshtasticProcessor) processNodeInfo(nodeID string, payload map[string]interface{}, receivedAt time.Time) error {
 	info := domain.NodeInfo{
 		NodeID:    nodeID,
 		LongName:  validator.SanitizeString(p.getString(payload, "longname")),
 		ShortName: validator.SanitizeString(p.getString(payload, "shortname")),
 		Hardware:  unknownValue,
+		HWModel:   unknownValue,
 		Role:      unknownValue,
-		Timestamp: time.Now(),
+		Timestamp: receivedAt,
 	}
 
 	// Устанавливаем значения по умолчанию, если поля пустые
@@ -300,6 +671,7 @@ func (p *MeshtasticProcessor) processNodeInfo(nodeID string, payload map[string]
 
 	if val, ok := payload["hardware"].(float64); ok {
 		info.Hardware = strconv.FormatFloat(val, 'f', 0, 64)
+		info.HWModel = domain.GetHardwareModelName(int(val))
 	}
 	if val, ok := payload["role"].(float64); ok {
 		info.Role = domain.GetRoleName(int(val))
@@ -329,8 +701,57 @@ func (p *MeshtasticProcessor) processTextMessage(nodeID string, _ map[string]int
 	return nil
 }
 
-func (p *MeshtasticProcessor) processPosition(nodeID string, _ map[string]interface{}) error {
-	p.collector.UpdateMessageCounter(nodeID, domain.MessageTypePosition)
+func (p *MeshtasticProcessor) processPosition(nodeID string, payload map[string]interface{}, receivedAt time.Time) error {
+	pos := p.parsePosition(nodeID, payload, receivedAt)
+	p.derived.observePosition(pos)
+	return p.collector.CollectPosition(pos)
+}
+
+func (p *MeshtasticProcessor) parsePosition(nodeID string, payload map[string]interface{}, receivedAt time.Time) domain.Position {
+	pos := domain.Position{
+		NodeID:    nodeID,
+		Timestamp: receivedAt,
+	}
+
+	pos.Latitude = p.getCoordinate(payload, "latitude_i", "latitude")
+	pos.Longitude = p.getCoordinate(payload, "longitude_i", "longitude")
+	// 0/0 означает отсутствие координат (proto3 default)
+	if pos.Latitude != nil && pos.Longitude != nil && *pos.Latitude == 0 && *pos.Longitude == 0 {
+		pos.Latitude, pos.Longitude = nil, nil
+	}
+
+	pos.Altitude = p.getInt32(payload, "altitude")
+	pos.SatsInView = p.getInt32(payload, "sats_in_view")
+	pos.PrecisionBits = p.getInt32(payload, "precision_bits")
+	return pos
+}
+
+// getCoordinate читает координату из целочисленного поля (1e-7 градуса) или из поля в градусах.
+func (p *MeshtasticProcessor) getCoordinate(payload map[string]interface{}, intKey, degreesKey string) *float64 {
+	if val, ok := payload[intKey].(float64); ok {
+		degrees := val / domain.PositionCoordinateDivider
+		return &degrees
+	}
+	if val, ok := payload[degreesKey].(float64); ok {
+		return &val
+	}
+	return nil
+}
+
+func getUint32(data map[string]interface{}, key string) *uint32 {
+	val, ok := data[key].(float64)
+	if !ok || val < 0 || val > math.MaxUint32 {
+		return nil
+	}
+	result := uint32(val)
+	return &result
+}
+
+func (p *MeshtasticProcessor) getInt32(payload map[string]interface{}, key string) *int32 {
+	if val, ok := payload[key].(float64); ok {
+		result := int32(val)
+		return &result
+	}
 	return nil
 }
 
@@ -339,9 +760,79 @@ func (p *MeshtasticProcessor) processWaypoint(nodeID string, _ map[string]interf
 	return nil
 }
 
-func (p *MeshtasticProcessor) processNeighborInfo(nodeID string, _ map[string]interface{}) error {
-	p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeNeighborInfo)
-	return nil
+func (p *MeshtasticProcessor) processNeighborInfo(nodeID string, payload map[string]interface{}, receivedAt time.Time) error {
+	info := domain.NeighborInfo{
+		NodeID:    nodeID,
+		Timestamp: receivedAt,
+	}
+	if val, ok := payload["node_broadcast_interval_secs"].(float64); ok {
+		info.NodeBroadcastIntervalSecs = int32(val)
+	}
+
+	entries, _ := payload["neighbors"].([]interface{})
+	for _, entry := range entries {
+		if neighbor, ok := p.parseNeighbor(entry); ok {
+			info.Neighbors = append(info.Neighbors, neighbor)
+		}
+	}
+
+	return p.collector.CollectNeighborInfo(info)
+}
+
+// processMapReport отчёт для карты: версия прошивки, регион, пресет модема и огрублённая позиция.
+func (p *MeshtasticProcessor) processMapReport(nodeID string, payload map[string]interface{}, receivedAt time.Time) error {
+	report := domain.MapReport{
+		NodeID:          nodeID,
+		FirmwareVersion: validator.SanitizeString(p.getString(payload, "firmware_version")),
+		Region:          enumName(payload, "region", domain.GetRegionName),
+		ModemPreset:     enumName(payload, "modem_preset", domain.GetModemPresetName),
+		Position:        p.parsePosition(nodeID, payload, receivedAt),
+		Timestamp:       receivedAt,
+	}
+	if report.FirmwareVersion == "" {
+		report.FirmwareVersion = unknownValue
+	}
+	report.HasDefaultChannel, _ = payload["has_default_channel"].(bool)
+	report.OnlineLocalNodes = p.getInt32(payload, "num_online_local_nodes")
+	report.Position.PrecisionBits = p.getInt32(payload, "position_precision")
+
+	p.derived.observePosition(report.Position)
+	return p.modules.CollectMapReport(report)
+}
+
+// enumName имя значения enum: из protobuf приходит номер, из JSON может прийти строка.
+// Отсутствующее поле — нулевое значение enum, как в proto3.
+func enumName(payload map[string]interface{}, key string, name func(int) string) string {
+	switch val := payload[key].(type) {
+	case float64:
+		return name(int(val))
+	case string:
+		return validator.SanitizeString(val)
+	}
+	return name(0)
+}
+
+func (p *MeshtasticProcessor) parseNeighbor(entry interface{}) (domain.Neighbor, bool) {
+	fields, ok := entry.(map[string]interface{})
+	if !ok {
+		return domain.Neighbor{}, false
+	}
+	id, ok := fields["node_id"].(float64)
+	if !ok || id <= 0 || id > math.MaxUint32 {
+		return domain.Neighbor{}, false
+	}
+
+	neighbor := domain.Neighbor{NeighborID: p.formatNodeID(uint32(id))}
+	if val, ok := fields["snr"].(float64); ok {
+		neighbor.SNR = roundToTwoDecimals(val)
+	}
+	if val, ok := fields["last_rx_time"].(float64); ok {
+		neighbor.LastRxTime = int64(val)
+	}
+	if val, ok := fields["node_broadcast_interval_secs"].(float64); ok {
+		neighbor.NodeBroadcastIntervalSecs = int32(val)
+	}
+	return neighbor, true
 }
 
 func (p *MeshtasticProcessor) determineTelemetryType(payload map[string]interface{}) string {
@@ -354,6 +845,9 @@ func (p *MeshtasticProcessor) determineTelemetryType(payload map[string]interfac
 	if _, ok := payload["barometric_pressure"]; ok {
 		return environmentMetricsType
 	}
+	if telemetryType, ok := sensorTelemetryType(payload); ok {
+		return telemetryType
+	}
 	if _, ok := payload["ch1_voltage"]; ok {
 		return powerMetricsType
 	}
@@ -368,3 +862,12 @@ func (p *MeshtasticProcessor) determineTelemetryType(payload map[string]interfac
 	}
 	return deviceMetricsType
 }
+
+func sensorTelemetryType(payload map[string]interface{}) (string, bool) {
+	for _, field := range domain.SensorFields {
+		if _, ok := payload[field.Field]; ok {
+			return field.Type, true
+		}
+	}
+	return "", false
+}
@@ -3,6 +3,8 @@ package application
 import (
 	"context"
 	"fmt"
+	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,6 +12,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"meshtastic-exporter/pkg/domain"
+	apperrors "meshtastic-exporter/pkg/errors"
+	"meshtastic-exporter/pkg/infrastructure"
 	"meshtastic-exporter/pkg/mocks"
 )
 
@@ -296,6 +300,10 @@ func TestMeshtasticProcessor_RSSI_SNR_Extraction(t *testing.T) {
 			err := processor.ProcessMessage(context.Background(), "msh/test", []byte(tt.payload))
 			require.NoError(t, err)
 
+			require.Len(t, mockCollector.Receptions, 1)
+			assert.Equal(t, tt.expRSSI, mockCollector.Receptions[0].RSSI)
+			assert.Equal(t, tt.expSNR, mockCollector.Receptions[0].SNR)
+
 			if len(mockCollector.TelemetryData) > 0 {
 				data := mockCollector.TelemetryData[0]
 				if tt.expRSSI != nil {
@@ -394,3 +402,194 @@ func TestExtractNetworkFields(t *testing.T) {
 	assert.Equal(t, -90.0, *data.RSSI)
 	assert.Equal(t, -5.0, *data.SNR)
 }
+
+// TestMeshtasticProcessor_InvalidMessageErrors битый JSON и сообщение без from —
+// ошибки валидации, как при отдельной проверке ValidateMeshtasticMessage.
+func TestMeshtasticProcessor_InvalidMessageErrors(t *testing.T) {
+	t.Parallel()
+	processor := NewMeshtasticProcessor(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, false, "")
+	tests := []struct {
+		name    string
+		payload string
+		cause   string
+	}{
+		{name: "invalid_json", payload: `{"from": 123,`, cause: "invalid JSON"},
+		{name: "array", payload: `[{"from": 123}]`, cause: "invalid JSON"},
+		{name: "missing_from", payload: `{"type": "text"}`, cause: "missing 'from' field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := processor.ProcessMessage(context.Background(), "msh/test", []byte(tt.payload))
+
+			var appErr *apperrors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, apperrors.ValidationError, appErr.Type)
+			assert.Equal(t, "invalid message", appErr.Message)
+			assert.Contains(t, appErr.Cause.Error(), tt.cause)
+		})
+	}
+}
+
+func TestMeshtasticProcessor_ParseMessage(t *testing.T) {
+	t.Parallel()
+	processor := NewMeshtasticProcessor(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, false, "")
+
+	msg, err := processor.parseMessage([]byte(benchmarkTelemetry))
+
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2837103652), msg.From)
+	assert.Equal(t, uint32(4294967295), msg.To)
+	assert.Equal(t, uint32(1193046), msg.ID)
+	assert.Equal(t, domain.MessageTypeTelemetry, msg.Type)
+	assert.Equal(t, "!f992bd54", msg.GatewayID)
+	assert.Equal(t, -97.0, *msg.RSSI)
+	assert.Equal(t, 6.25, *msg.SNR)
+	assert.Equal(t, uint32(3), *msg.HopStart)
+	assert.Nil(t, msg.HopLimit)
+	assert.Equal(t, uint32(1), *msg.HopsAway)
+	assert.Equal(t, uint32(1735689600), msg.RxTime)
+	assert.Equal(t, 87.0, msg.Payload["battery_level"])
+}
+
+// Поля неподходящего типа разбираются запасным путём через map, как раньше.
+// TestMeshtasticProcessor_ParseMessageLooseTypes поля конверта неподходящего
+// типа приводятся по прежним правилам map без повторного разбора.
+func TestMeshtasticProcessor_ParseMessageLooseTypes(t *testing.T) {
+	t.Parallel()
+	processor := NewMeshtasticProcessor(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, false, "")
+	tests := []struct {
+		name    string
+		payload string
+		want    domain.MeshtasticMessage
+		wantErr bool
+	}{
+		{
+			name:    "fractional_from",
+			payload: `{"from": 123.7, "type": "text", "payload": {"text": "hi"}}`,
+			want:    domain.MeshtasticMessage{From: 123, Type: "text", Payload: map[string]interface{}{"text": "hi"}, RawPayload: []byte(`{"text": "hi"}`)},
+		},
+		{
+			name:    "negative_hop_limit",
+			payload: `{"from": 123, "type": "text", "hop_limit": -1, "sender": 42}`,
+			want:    domain.MeshtasticMessage{From: 123, Type: "text"},
+		},
+		{
+			name:    "sendtext",
+			payload: `{"from": 123, "type": "sendtext", "payload": "hello"}`,
+			want:    domain.MeshtasticMessage{From: 123, Type: "sendtext", Payload: map[string]interface{}{"text": "hello"}, RawPayload: []byte(`"hello"`)},
+		},
+		{
+			name:    "string_from",
+			payload: `{"from": "!0000007b", "type": "text"}`,
+			want:    domain.MeshtasticMessage{Type: "text"},
+		},
+		{
+			name:    "null_from",
+			payload: `{"from": null, "type": "text"}`,
+			want:    domain.MeshtasticMessage{Type: "text"},
+		},
+		{
+			name:    "escaped_sender",
+			payload: `{"from": 123, "type": "text", "sender": "\u0021f992bd54"}`,
+			want:    domain.MeshtasticMessage{From: 123, Type: "text", GatewayID: "!f992bd54"},
+		},
+		{
+			name:    "missing_from",
+			payload: `{"type": "text", "payload": {"text": "hi"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "array",
+			payload: `[{"from": 123}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid_json",
+			payload: `{"from": 123,`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			msg, err := processor.parseMessage([]byte(tt.payload))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, msg)
+		})
+	}
+}
+
+// minRateEnv минимальная пропускная способность msgs/s для
+// BenchmarkMeshtasticProcessor_ProcessMessage; без переменной бенчмарк только
+// выводит скорость, результат зависит от машины.
+const minRateEnv = "MESHTASTIC_BENCH_MIN_RATE"
+
+const benchmarkTelemetry = `{
+	"channel": 0,
+	"from": 2837103652,
+	"hop_start": 3,
+	"hops_away": 1,
+	"id": 1193046,
+	"payload": {
+		"air_util_tx": 1.2,
+		"battery_level": 87,
+		"channel_utilization": 11.4,
+		"uptime_seconds": 86400,
+		"voltage": 4.05
+	},
+	"rssi": -97,
+	"sender": "!f992bd54",
+	"snr": 6.25,
+	"timestamp": 1735689600,
+	"to": 4294967295,
+	"type": "telemetry"
+}`
+
+// BenchmarkMeshtasticProcessor_ProcessMessage сообщения в секунду на ядро с настоящим
+// коллектором Prometheus: бенчмарк идёт в одной горутине.
+func BenchmarkMeshtasticProcessor_ProcessMessage(b *testing.B) {
+	collector := infrastructure.NewPrometheusCollector()
+	processor := NewMeshtasticProcessor(collector, &mocks.MockAlertSender{}, false, "")
+	payload := []byte(benchmarkTelemetry)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := processor.ProcessMessage(ctx, "msh/EU_868/2/json/LongFast/!f992bd54", payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+	rate := float64(b.N) / b.Elapsed().Seconds()
+	b.ReportMetric(rate, "msgs/s")
+
+	value := os.Getenv(minRateEnv)
+	if value == "" {
+		return
+	}
+	minRate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		b.Fatalf("invalid %s: %v", minRateEnv, err)
+	}
+	if rate < minRate {
+		b.Errorf("throughput %.0f msgs/s per core, minimum %.0f", rate, minRate)
+	}
+}
+
+func BenchmarkMeshtasticProcessor_ParseMessage(b *testing.B) {
+	processor := NewMeshtasticProcessor(&mocks.MockMetricsCollector{}, &mocks.MockAlertSender{}, false, "")
+	payload := []byte(benchmarkTelemetry)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := processor.parseMessage(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -54,6 +56,30 @@ func TestMeshtasticProcessor_ProcessMessage_Position(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.True(t, mockCollector.UpdateNodeLastSeenCalled)
+	require.Len(t, mockCollector.PositionData, 1)
+	pos := mockCollector.PositionData[0]
+	assert.Equal(t, "123456789", pos.NodeID)
+	assert.InDelta(t, 55.9748544, *pos.Latitude, 1e-9)
+	assert.InDelta(t, 37.3418112, *pos.Longitude, 1e-9)
+	assert.Equal(t, int32(150), *pos.Altitude)
+	assert.Equal(t, int32(8), *pos.SatsInView)
+	assert.Equal(t, int32(32), *pos.PrecisionBits)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_PositionWithoutFix(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{"from": 123456789, "type": "position", "payload": {"latitude_i": 0, "longitude_i": 0, "sats_in_view": 0}}`)
+
+	err := processor.ProcessMessage(context.Background(), "msh/test", payload)
+
+	require.NoError(t, err)
+	require.Len(t, mockCollector.PositionData, 1)
+	assert.Nil(t, mockCollector.PositionData[0].Latitude)
+	assert.Nil(t, mockCollector.PositionData[0].Longitude)
+	assert.Equal(t, int32(0), *mockCollector.PositionData[0].SatsInView)
 }
 
 func TestMeshtasticProcessor_ProcessMessage_Waypoint(t *testing.T) {
@@ -93,10 +119,13 @@ func TestMeshtasticProcessor_ProcessMessage_NeighborInfo(t *testing.T) {
 		"from": 123456789,
 		"type": "neighborinfo",
 		"payload": {
-			"node_id": 987654321,
-			"snr": 12.5,
-			"last_rx_time": 1640995200,
-			"node_broadcast_interval_secs": 900
+			"node_id": 123456789,
+			"node_broadcast_interval_secs": 900,
+			"neighbors": [
+				{"node_id": 987654321, "snr": 12.5, "last_rx_time": 1640995200},
+				{"node_id": 0, "snr": 1.0},
+				{"node_id": 111111111, "snr": -3.25, "node_broadcast_interval_secs": 300}
+			]
 		}
 	}`)
 
@@ -104,6 +133,13 @@ func TestMeshtasticProcessor_ProcessMessage_NeighborInfo(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.True(t, mockCollector.UpdateNodeLastSeenCalled)
+	require.Len(t, mockCollector.NeighborInfoData, 1)
+	info := mockCollector.NeighborInfoData[0]
+	assert.Equal(t, "123456789", info.NodeID)
+	assert.Equal(t, int32(900), info.NodeBroadcastIntervalSecs)
+	require.Len(t, info.Neighbors, 2)
+	assert.Equal(t, domain.Neighbor{NeighborID: "987654321", SNR: 12.5, LastRxTime: 1640995200}, info.Neighbors[0])
+	assert.Equal(t, domain.Neighbor{NeighborID: "111111111", SNR: -3.25, NodeBroadcastIntervalSecs: 300}, info.Neighbors[1])
 }
 
 func TestMeshtasticProcessor_DetermineTelemetryType(t *testing.T) {
@@ -130,6 +166,21 @@ func TestMeshtasticProcessor_DetermineTelemetryType(t *testing.T) {
 			payload:  map[string]interface{}{"barometric_pressure": 1013.25},
 			expected: domain.TelemetryTypeEnvironment,
 		},
+		{
+			name:     "environment metrics - wind speed",
+			payload:  map[string]interface{}{"wind_speed": 4.2},
+			expected: domain.TelemetryTypeEnvironment,
+		},
+		{
+			name:     "air quality metrics - pm25",
+			payload:  map[string]interface{}{"pm25": 12.0, "co2": 640.0},
+			expected: domain.TelemetryTypeAirQuality,
+		},
+		{
+			name:     "local stats",
+			payload:  map[string]interface{}{"uptime_seconds": 3600.0, "num_packets_tx": 120.0, "num_online_nodes": 14.0},
+			expected: domain.TelemetryTypeLocalStats,
+		},
 		{
 			name:     "power metrics - ch1 voltage",
 			payload:  map[string]interface{}{"ch1_voltage": 12.5},
@@ -201,3 +252,361 @@ func TestMeshtasticProcessor_ProcessMessage_TelemetryWithType(t *testing.T) {
 	err = processor.ProcessMessage(context.Background(), "msh/test", powerPayload)
 	require.NoError(t, err)
 }
+
+func TestMeshtasticProcessor_ProcessMessage_WeatherStation(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{
+		"from": 123456789,
+		"type": "telemetry",
+		"payload": {
+			"lux": 1250.456,
+			"wind_speed": 3.5,
+			"wind_direction": 270,
+			"rainfall_1h": 0.8,
+			"soil_temperature": 12.25
+		}
+	}`)
+
+	err := processor.ProcessMessage(context.Background(), "msh/test", payload)
+
+	require.NoError(t, err)
+	require.Len(t, mockCollector.TelemetryData, 1)
+	data := mockCollector.TelemetryData[0]
+	assert.Equal(t, domain.TelemetryTypeEnvironment, data.Type)
+	assert.Equal(t, map[string]float64{
+		domain.MetricLux:             1250.45,
+		domain.MetricWindSpeed:       3.5,
+		domain.MetricWindDirection:   270,
+		domain.MetricRainfall1h:      0.8,
+		domain.MetricSoilTemperature: 12.25,
+	}, data.Sensors)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_LocalStats(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{
+		"from": 123456789,
+		"type": "telemetry",
+		"payload": {
+			"uptime_seconds": 86400,
+			"channel_utilization": 18.5,
+			"num_packets_tx": 1520,
+			"num_packets_rx": 8410,
+			"num_packets_rx_bad": 37,
+			"num_rx_dupe": 2210,
+			"num_online_nodes": 23,
+			"num_total_nodes": 96
+		}
+	}`)
+
+	err := processor.ProcessMessage(context.Background(), "msh/test", payload)
+
+	require.NoError(t, err)
+	require.Len(t, mockCollector.TelemetryData, 1)
+	data := mockCollector.TelemetryData[0]
+	assert.Equal(t, domain.TelemetryTypeLocalStats, data.Type)
+	assert.Equal(t, 86400.0, *data.UptimeSeconds)
+	assert.Equal(t, 18.5, *data.ChannelUtilization)
+	assert.Equal(t, 1520.0, data.Sensors[domain.MetricLocalPacketsTx])
+	assert.Equal(t, 37.0, data.Sensors[domain.MetricLocalPacketsRxBad])
+	assert.Equal(t, 2210.0, data.Sensors[domain.MetricLocalPacketsRxDupe])
+	assert.Equal(t, 96.0, data.Sensors[domain.MetricLocalTotalNodes])
+}
+
+func TestMeshtasticProcessor_ProcessMessage_HopsAway(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		payload  string
+		expected int
+		counted  bool
+	}{
+		{
+			name:     "hop_start and hop_limit",
+			payload:  `{"from": 123456789, "type": "text", "hop_start": 3, "hop_limit": 1, "payload": {"text": "hi"}}`,
+			expected: 2,
+			counted:  true,
+		},
+		{
+			name:     "hops_away from firmware",
+			payload:  `{"from": 123456789, "type": "text", "hops_away": 4, "payload": {"text": "hi"}}`,
+			expected: 4,
+			counted:  true,
+		},
+		{
+			name:    "old firmware without hop_start",
+			payload: `{"from": 123456789, "type": "text", "hop_limit": 3, "payload": {"text": "hi"}}`,
+		},
+		{
+			name:    "hop_limit above hop_start",
+			payload: `{"from": 123456789, "type": "text", "hop_start": 1, "hop_limit": 3, "payload": {"text": "hi"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCollector := &mocks.MockMetricsCollector{}
+			processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+			err := processor.ProcessMessage(context.Background(), "msh/test", []byte(tc.payload))
+
+			require.NoError(t, err)
+			hops, counted := mockCollector.PacketHops["123456789"]
+			assert.Equal(t, tc.counted, counted)
+			assert.Equal(t, tc.expected, hops)
+		})
+	}
+}
+
+func TestMeshtasticProcessor_ProcessMessage_RelayNode(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{"from": 123456789, "type": "text", "relay_node": 84, "payload": {"text": "hi"}}`)
+
+	err := processor.ProcessMessage(context.Background(), "msh/test", payload)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0x54"}, mockCollector.RelayNodes)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_GatewayAttribution(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		topic    string
+		payload  string
+		expected string
+	}{
+		{
+			name:     "sender field",
+			topic:    "msh/EU_868/2/json/LongFast/!aaaa0002",
+			payload:  `{"from": 123456789, "sender": "!aaaa0001", "type": "text", "rssi": -80, "snr": 9.5, "payload": {"text": "hi"}}`,
+			expected: "!aaaa0001",
+		},
+		{
+			name:     "last topic segment",
+			topic:    "msh/EU_868/2/json/LongFast/!aaaa0002",
+			payload:  `{"from": 123456789, "type": "text", "rssi": -80, "snr": 9.5, "payload": {"text": "hi"}}`,
+			expected: "!aaaa0002",
+		},
+		{
+			name:    "unknown gateway",
+			topic:   "msh/test",
+			payload: `{"from": 123456789, "type": "text", "payload": {"text": "hi"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCollector := &mocks.MockMetricsCollector{}
+			processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+			err := processor.ProcessMessage(context.Background(), tc.topic, []byte(tc.payload))
+
+			require.NoError(t, err)
+			require.Len(t, mockCollector.Receptions, 1)
+			assert.Equal(t, "123456789", mockCollector.Receptions[0].NodeID)
+			assert.Equal(t, tc.expected, mockCollector.Receptions[0].GatewayID)
+			assert.Equal(t, domain.MessageTypeText, mockCollector.Receptions[0].MessageType)
+		})
+	}
+}
+
+func TestMeshtasticProcessor_ProcessMessage_TopicTemplate(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		TopicTemplate: domain.DefaultTopicTemplate,
+	})
+
+	payload := []byte(`{"from": 123456789, "type": "text", "payload": {"text": "hi"}}`)
+
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/EU_868/2/json/LongFast/!aaaa0001", payload))
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/US/2/json/MediumSlow/!bbbb0002", payload))
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/2/json/LongFast/!cccc0003", payload))
+
+	require.Len(t, mockCollector.Receptions, 3)
+	assert.Equal(t, domain.Reception{
+		NodeID: "123456789", GatewayID: "!aaaa0001", Region: "EU_868", Channel: "LongFast", MessageType: domain.MessageTypeText,
+	}, mockCollector.Receptions[0])
+	assert.Equal(t, "US", mockCollector.Receptions[1].Region)
+	assert.Equal(t, "MediumSlow", mockCollector.Receptions[1].Channel)
+	// топик не подходит под шаблон: шлюз из последнего сегмента, регион и канал неизвестны
+	assert.Equal(t, domain.Reception{
+		NodeID: "123456789", GatewayID: "!cccc0003", MessageType: domain.MessageTypeText,
+	}, mockCollector.Receptions[2])
+}
+
+func TestMeshtasticProcessor_ProcessMessage_HexNodeIDFormat(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		NodeIDFormat: domain.NodeIDFormatHex,
+	})
+
+	payload := []byte(`{
+		"from": 4187143508,
+		"type": "neighborinfo",
+		"payload": {"neighbors": [{"node_id": 26, "snr": 4.5}]}
+	}`)
+
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+	require.Len(t, mockCollector.NeighborInfoData, 1)
+	info := mockCollector.NeighborInfoData[0]
+	assert.Equal(t, "!f992bd54", info.NodeID)
+	require.Len(t, info.Neighbors, 1)
+	assert.Equal(t, "!0000001a", info.Neighbors[0].NeighborID)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_DerivedMetrics(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		DerivedMetrics: true,
+	})
+
+	telemetry := []byte(`{"from": 123456789, "type": "telemetry", "payload": {"temperature": 20, "relative_humidity": 50, "barometric_pressure": 1000}}`)
+	position := []byte(`{"from": 123456789, "type": "position", "payload": {"latitude_i": 557558000, "longitude_i": 376173000, "altitude": 500}}`)
+
+	// до первой позиции высота неизвестна, давление не приводится
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", telemetry))
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", position))
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", telemetry))
+
+	require.Len(t, mockCollector.TelemetryData, 2)
+	first := mockCollector.TelemetryData[0].Sensors
+	assert.InDelta(t, 9.26, first[domain.MetricDewPoint], 0.01)
+	assert.InDelta(t, 19.36, first[domain.MetricHeatIndex], 0.01)
+	assert.InDelta(t, 8.64, first[domain.MetricAbsoluteHumidity], 0.01)
+	assert.NotContains(t, first, domain.MetricSeaLevelPressure)
+	assert.InDelta(t, 1059.67, mockCollector.TelemetryData[1].Sensors[domain.MetricSeaLevelPressure], 0.01)
+}
+
+func TestDerivedMetrics_AltitudeTTL(t *testing.T) {
+	t.Parallel()
+	derived := newDerivedMetrics(true, time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	altitude := int32(500)
+	pressure := 1000.0
+
+	derived.observePosition(domain.Position{NodeID: "123", Altitude: &altitude, Timestamp: start})
+	fresh := domain.TelemetryData{NodeID: "123", BarometricPressure: &pressure, Timestamp: start.Add(30 * time.Minute)}
+	derived.apply(&fresh)
+	assert.Contains(t, fresh.Sensors, domain.MetricSeaLevelPressure)
+
+	// позиция старше TTL, её метрики уже удалены
+	stale := domain.TelemetryData{NodeID: "123", BarometricPressure: &pressure, Timestamp: start.Add(2 * time.Hour)}
+	derived.apply(&stale)
+	assert.NotContains(t, stale.Sensors, domain.MetricSeaLevelPressure)
+
+	derived.observePosition(domain.Position{NodeID: "456", Altitude: &altitude, Timestamp: start.Add(2 * time.Hour)})
+	assert.NotContains(t, derived.altitudes, "123")
+	assert.Contains(t, derived.altitudes, "456")
+}
+
+func TestMeshtasticProcessor_ProcessMessage_DerivedMetricsDisabled(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{"from": 123456789, "type": "telemetry", "payload": {"temperature": 20, "relative_humidity": 50}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, mockCollector.TelemetryData, 1)
+	assert.Empty(t, mockCollector.TelemetryData[0].Sensors)
+}
+
+func TestReceivedAt(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		rxTime   uint32
+		expected time.Time
+	}{
+		{"no rx_time", 0, now},
+		{"gateway rx_time", 1699999990, time.Unix(1699999990, 0)},
+		{"rx_time in future", 1700000100, now},
+		{"gateway clock far behind", 1600000000, now},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, receivedAt(tt.rxTime, now), tt.name)
+	}
+}
+
+func TestMeshtasticProcessor_ProcessMessage_RxTime(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	rx := time.Now().Add(-42 * time.Second).Truncate(time.Second)
+	payload := []byte(fmt.Sprintf(`{"from": 123456789, "type": "telemetry", "sender": "!aaaa0001", "timestamp": %d, "payload": {"battery_level": 80}}`, rx.Unix()))
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, mockCollector.Receptions, 1)
+	assert.True(t, rx.Equal(mockCollector.Receptions[0].RxTime))
+	require.Len(t, mockCollector.TelemetryData, 1)
+	assert.True(t, rx.Equal(mockCollector.TelemetryData[0].Timestamp))
+}
+
+func TestParseDetection(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		text     string
+		expected domain.DetectionEvent
+		ok       bool
+	}{
+		{"Front door detected", domain.DetectionEvent{Sensor: "Front door", Triggered: true, State: 1}, true},
+		{"Motion detected\a", domain.DetectionEvent{Sensor: "Motion", Triggered: true, State: 1}, true},
+		{"Front door state: 0", domain.DetectionEvent{Sensor: "Front door", State: 0}, true},
+		{"Motion state: 1\r\n", domain.DetectionEvent{Sensor: "Motion", State: 1}, true},
+		{"Front door state: open", domain.DetectionEvent{}, false},
+		{"hello", domain.DetectionEvent{}, false},
+	}
+
+	for _, tt := range tests {
+		event, ok := parseDetection(tt.text)
+		assert.Equal(t, tt.ok, ok, tt.text)
+		assert.Equal(t, tt.expected, event, tt.text)
+	}
+}
+
+func TestMeshtasticProcessor_ProcessMessage_Detection(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{"from": 123456789, "type": "detection", "payload": {"text": "Front door detected"}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, mockCollector.DetectionEvents, 1)
+	assert.Equal(t, "123456789", mockCollector.DetectionEvents[0].NodeID)
+	assert.Equal(t, "Front door", mockCollector.DetectionEvents[0].Sensor)
+	assert.True(t, mockCollector.DetectionEvents[0].Triggered)
+}
+
+func TestMeshtasticProcessor_ProcessMessage_Paxcounter(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(mockCollector, &mocks.MockAlertSender{}, false, "")
+
+	payload := []byte(`{"from": 123456789, "type": "paxcounter", "payload": {"wifi_count": 12, "ble_count": 30, "uptime": 3600}}`)
+	require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+
+	require.Len(t, mockCollector.Paxcounts, 1)
+	assert.Equal(t, uint32(12), mockCollector.Paxcounts[0].WiFi)
+	assert.Equal(t, uint32(30), mockCollector.Paxcounts[0].BLE)
+}
@@ -0,0 +1,93 @@
+package application
+
+import (
+	"strconv"
+	"strings"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/validator"
+)
+
+// Форматы текста модулей прошивки
+const (
+	detectionTriggeredSuffix = " detected" // "<name> detected"
+	detectionStateSeparator  = " state: "  // "<name> state: <0|1>"
+	rangeTestPrefix          = "seq "      // "seq <N>"
+	// bellCharacter прошивка дописывает к срабатыванию датчика, если включён send_bell
+	bellCharacter = "\a"
+)
+
+// processDetection сообщение Detection Sensor: срабатывание при смене состояния пина
+// и отчёт о текущем состоянии раз в state_broadcast_secs. Текст другого вида только считается.
+func (p *MeshtasticProcessor) processDetection(nodeID string, msg domain.MeshtasticMessage) error {
+	event, ok := parseDetection(p.getString(msg.Payload, "text"))
+	if !ok {
+		p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeDetection)
+		return nil
+	}
+	event.NodeID = nodeID
+	event.Timestamp = msg.ReceivedAt
+	return p.modules.CollectDetection(event)
+}
+
+func parseDetection(text string) (domain.DetectionEvent, bool) {
+	text = strings.TrimSpace(strings.Trim(text, bellCharacter))
+	if name, ok := strings.CutSuffix(text, detectionTriggeredSuffix); ok {
+		return domain.DetectionEvent{Sensor: sensorName(name), Triggered: true, State: 1}, true
+	}
+	if name, state, ok := strings.Cut(text, detectionStateSeparator); ok {
+		value, err := strconv.ParseFloat(strings.TrimSpace(state), 64)
+		if err != nil {
+			return domain.DetectionEvent{}, false
+		}
+		return domain.DetectionEvent{Sensor: sensorName(name), State: value}, true
+	}
+	return domain.DetectionEvent{}, false
+}
+
+func sensorName(name string) string {
+	if name = validator.SanitizeString(strings.TrimSpace(name)); name != "" {
+		return name
+	}
+	return unknownValue
+}
+
+// processPaxcount число устройств WiFi и BLE, которые видит Paxcounter.
+func (p *MeshtasticProcessor) processPaxcount(nodeID string, msg domain.MeshtasticMessage) error {
+	pax := domain.Paxcount{
+		NodeID:    nodeID,
+		Timestamp: msg.ReceivedAt,
+	}
+	if wifi := getUint32(msg.Payload, "wifi_count"); wifi != nil {
+		pax.WiFi = *wifi
+	}
+	if ble := getUint32(msg.Payload, "ble_count"); ble != nil {
+		pax.BLE = *ble
+	}
+	return p.modules.CollectPaxcount(pax)
+}
+
+// processRangeTest пакет "seq N", который отправитель Range Test шлёт с интервалом sender.
+// Приёмником выступает шлюз, его RSSI/SNR и есть результат теста.
+func (p *MeshtasticProcessor) processRangeTest(nodeID string, msg domain.MeshtasticMessage) error {
+	text := strings.TrimSpace(p.getString(msg.Payload, "text"))
+	seq, ok := strings.CutPrefix(text, rangeTestPrefix)
+	if !ok {
+		p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeRangeTest)
+		return nil
+	}
+	sequence, err := strconv.ParseUint(strings.TrimSpace(seq), 10, 32)
+	if err != nil {
+		p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeRangeTest)
+		return nil
+	}
+
+	return p.modules.CollectRangeTest(domain.RangeTestPacket{
+		NodeID:    nodeID,
+		GatewayID: msg.GatewayID,
+		Sequence:  uint32(sequence),
+		RSSI:      msg.RSSI,
+		SNR:       msg.SNR,
+		Timestamp: msg.ReceivedAt,
+	})
+}
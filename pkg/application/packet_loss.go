@@ -0,0 +1,207 @@
+package application
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+// recentPacketIDs сколько последних id пакета ноды помнит трекер: копии от шлюзов
+// приходят в пределах секунд, между ними у ноды бывает лишь несколько пакетов.
+const recentPacketIDs = 16
+
+type deliverySample struct {
+	at       time.Time
+	expected int
+	received int
+}
+
+type packetCounter struct {
+	value uint32
+	// sequential прошлый шаг счётчика был правдоподобным
+	sequential bool
+}
+
+// nodeDelivery состояние оценки потерь одной ноды.
+type nodeDelivery struct {
+	// advertised интервалы, которые нода сообщила сама, важнее интервалов из конфигурации
+	advertised map[string]time.Duration
+	lastSeen   map[string]time.Time
+	counter    packetCounter
+	hasCounter bool
+	recentIDs  []uint32
+	samples    []deliverySample
+	// expected и received суммы по samples
+	expected int
+	received int
+	updated  time.Time
+}
+
+// deliveryTracker оценивает потери пакетов. Если id пакетов ноды идут по счётчику,
+// ожидаемые пакеты — шаг счётчика с прошлого приёма, иначе — число интервалов рассылки
+// потока с прошлого пакета. Доля принятых считается за окно. Копии пакета от других
+// шлюзов трекер отбрасывает сам по последним id ноды, даже без дедупликации.
+// Состояние ноды, от которой ничего не было дольше TTL, удаляется, как и её метрики.
+type deliveryTracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	ttl       time.Duration
+	intervals map[string]time.Duration
+	nodes     map[string]*nodeDelivery
+	lastPrune time.Time
+}
+
+func newDeliveryTracker(cfg domain.PacketLossConfig, ttl time.Duration) *deliveryTracker {
+	if !cfg.Enabled {
+		return nil
+	}
+	intervals := cfg.Intervals
+	if len(intervals) == 0 {
+		intervals = domain.DefaultBroadcastIntervals
+	}
+	d := &deliveryTracker{
+		window:    cfg.Window,
+		ttl:       ttl,
+		intervals: intervals,
+		nodes:     make(map[string]*nodeDelivery),
+	}
+	if d.window <= 0 {
+		d.window = domain.DefaultPacketLossWindow
+	}
+	if d.ttl <= 0 {
+		d.ttl = domain.DefaultMetricsTTL
+	}
+	return d
+}
+
+// node состояние ноды, создаётся при первом обращении.
+func (d *deliveryTracker) node(nodeID string) *nodeDelivery {
+	node, ok := d.nodes[nodeID]
+	if !ok {
+		node = &nodeDelivery{
+			advertised: make(map[string]time.Duration),
+			lastSeen:   make(map[string]time.Time),
+		}
+		d.nodes[nodeID] = node
+	}
+	return node
+}
+
+// prune удаляет ноды, от которых ничего не было дольше TTL, не чаще раза в TTL.
+func (d *deliveryTracker) prune(now time.Time) {
+	if now.Sub(d.lastPrune) <= d.ttl {
+		return
+	}
+	d.lastPrune = now
+	for nodeID, node := range d.nodes {
+		if now.Sub(node.updated) > d.ttl {
+			delete(d.nodes, nodeID)
+		}
+	}
+}
+
+// advertise запоминает интервал рассылки потока, который сообщила нода
+// (node_broadcast_interval_secs в neighborinfo).
+func (d *deliveryTracker) advertise(nodeID, stream string, interval time.Duration) {
+	if d == nil || interval <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.node(nodeID).advertised[stream] = interval
+}
+
+// observe учитывает принятый пакет, false — оценить потери по нему нельзя.
+func (d *deliveryTracker) observe(nodeID, stream string, packetID uint32, now time.Time) (domain.DeliveryStats, bool) {
+	if d == nil {
+		return domain.DeliveryStats{}, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prune(now)
+	node := d.node(nodeID)
+	node.updated = now
+	if node.repeated(packetID) {
+		return domain.DeliveryStats{}, false
+	}
+	interval, tracked := d.interval(node, stream)
+	previous, seen := node.lastSeen[stream]
+	if tracked {
+		node.lastSeen[stream] = now
+	}
+
+	stats := domain.DeliveryStats{NodeID: nodeID, Stream: stream, Expected: 1, Received: 1}
+	step, sequential := node.counterStep(packetID)
+	switch {
+	case sequential:
+		stats.Expected = step
+	case !tracked:
+		return domain.DeliveryStats{}, false
+	case seen:
+		// пропущенным считаем пакет, если пауза больше полутора интервалов
+		stats.Expected = max(1, int(math.Round(now.Sub(previous).Seconds()/interval.Seconds())))
+	}
+	stats.Missed = stats.Expected - stats.Received
+	stats.Ratio = node.record(now.Add(-d.window), deliverySample{at: now, expected: stats.Expected, received: stats.Received})
+
+	return stats, true
+}
+
+func (d *deliveryTracker) interval(node *nodeDelivery, stream string) (time.Duration, bool) {
+	if interval, ok := node.advertised[stream]; ok {
+		return interval, true
+	}
+	interval, ok := d.intervals[stream]
+	return interval, ok && interval > 0
+}
+
+// repeated копия уже учтённого пакета ноды. Пакеты без id не сравниваются.
+func (n *nodeDelivery) repeated(packetID uint32) bool {
+	if packetID == 0 {
+		return false
+	}
+	if slices.Contains(n.recentIDs, packetID) {
+		return true
+	}
+	if len(n.recentIDs) == recentPacketIDs {
+		n.recentIDs = n.recentIDs[1:]
+	}
+	n.recentIDs = append(n.recentIDs, packetID)
+	return false
+}
+
+// counterStep сколько пакетов нода отправила с прошлого принятого по счётчику в id.
+// Счётчику верим, только если и прошлый шаг был правдоподобным: у старых прошивок
+// id случайные, после перезагрузки счётчик начинается со случайного значения.
+func (n *nodeDelivery) counterStep(packetID uint32) (int, bool) {
+	if packetID == 0 {
+		return 0, false
+	}
+	value := packetID & domain.PacketIDCounterMask
+	step := int((value - n.counter.value) & domain.PacketIDCounterMask)
+	plausible := n.hasCounter && step >= 1 && step <= domain.MaxPacketIDGap
+	sequential := plausible && n.counter.sequential
+	n.counter = packetCounter{value: value, sequential: plausible}
+	n.hasCounter = true
+	return step, sequential
+}
+
+// record добавляет пакет в окно ноды и возвращает долю принятых пакетов за окно.
+// Суммы окна ведутся по ходу: выпавшие из окна пакеты вычитаются, новый прибавляется.
+func (n *nodeDelivery) record(cutoff time.Time, sample deliverySample) float64 {
+	start := 0
+	for start < len(n.samples) && n.samples[start].at.Before(cutoff) {
+		n.expected -= n.samples[start].expected
+		n.received -= n.samples[start].received
+		start++
+	}
+	n.samples = append(n.samples[start:], sample)
+	n.expected += sample.expected
+	n.received += sample.received
+	return float64(n.received) / float64(n.expected)
+}
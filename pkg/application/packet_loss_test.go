@@ -0,0 +1,199 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+func TestDeliveryTracker_Gaps(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{
+		Enabled:   true,
+		Intervals: map[string]time.Duration{domain.TelemetryTypeDevice: 30 * time.Minute},
+	}, 24*time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first, ok := tracker.observe("123", domain.TelemetryTypeDevice, 0, start)
+	require.True(t, ok)
+	assert.Equal(t, domain.DeliveryStats{NodeID: "123", Stream: domain.TelemetryTypeDevice, Expected: 1, Received: 1, Ratio: 1}, first)
+
+	// небольшой джиттер пропуском не считается
+	onTime, _ := tracker.observe("123", domain.TelemetryTypeDevice, 0, start.Add(40*time.Minute))
+	assert.Equal(t, 1, onTime.Expected)
+	assert.Equal(t, 0, onTime.Missed)
+
+	// пауза в 2 часа: три пакета потеряно
+	gap, _ := tracker.observe("123", domain.TelemetryTypeDevice, 0, start.Add(160*time.Minute))
+	assert.Equal(t, 4, gap.Expected)
+	assert.Equal(t, 3, gap.Missed)
+	assert.InDelta(t, 3.0/6.0, gap.Ratio, 0.001)
+}
+
+func TestDeliveryTracker_PacketIDCounter(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{Enabled: true}, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// счётчику верим после двух правдоподобных шагов подряд
+	_, ok := tracker.observe("123", domain.MessageTypeText, 0xabc003ff, start)
+	assert.False(t, ok, "untracked stream without counter history")
+	_, ok = tracker.observe("123", domain.MessageTypeText, 0x12300000, start.Add(time.Minute))
+	assert.False(t, ok)
+
+	// счётчик перешёл через 0x3ff, потеряно два пакета, поток текста тоже учитывается
+	gap, ok := tracker.observe("123", domain.MessageTypeText, 0x55400003, start.Add(2*time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, 3, gap.Expected)
+	assert.Equal(t, 2, gap.Missed)
+	assert.InDelta(t, 1.0/3.0, gap.Ratio, 0.001)
+
+	// скачок счётчика — перезагрузка или случайные id, по нему не считаем
+	reboot, ok := tracker.observe("123", domain.TelemetryTypeDevice, 0x00000200, start.Add(3*time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, 1, reboot.Expected)
+}
+
+func TestDeliveryTracker_RepeatedPacketID(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{Enabled: true}, 0)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// копии от других шлюзов при dedup_window: 0s не сбрасывают счётчик
+	for i, id := range []uint32{0x00000001, 0x00000002, 0x00000002, 0x00000001} {
+		tracker.observe("123", domain.TelemetryTypeDevice, id, start.Add(time.Duration(i)*time.Second))
+	}
+	copyStats, ok := tracker.observe("123", domain.TelemetryTypeDevice, 0x00000002, start.Add(5*time.Second))
+	assert.False(t, ok)
+	assert.Zero(t, copyStats)
+
+	next, ok := tracker.observe("123", domain.TelemetryTypeDevice, 0x00000004, start.Add(time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, 2, next.Expected)
+	assert.Equal(t, 1, next.Missed)
+
+	// по интервалам копия тоже не считается ещё одним принятым пакетом
+	_, _ = tracker.observe("456", domain.TelemetryTypeDevice, 0x7f000010, start)
+	_, ok = tracker.observe("456", domain.TelemetryTypeDevice, 0x7f000010, start.Add(time.Second))
+	assert.False(t, ok)
+}
+
+func TestDeliveryTracker_AdvertisedInterval(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{Enabled: true}, 24*time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, ok := tracker.observe("123", domain.MessageTypeNeighborInfo, 0, start)
+	assert.False(t, ok, "neighborinfo has no default interval")
+
+	tracker.advertise("123", domain.MessageTypeNeighborInfo, 15*time.Minute)
+	tracker.advertise("123", domain.MessageTypeNodeInfo, time.Hour)
+	_, ok = tracker.observe("123", domain.MessageTypeNeighborInfo, 0, start)
+	require.True(t, ok)
+	gap, _ := tracker.observe("123", domain.MessageTypeNeighborInfo, 0, start.Add(time.Hour))
+	assert.Equal(t, 4, gap.Expected)
+
+	// интервал ноды важнее интервала по умолчанию (3h)
+	_, _ = tracker.observe("123", domain.MessageTypeNodeInfo, 0, start)
+	nodeInfo, _ := tracker.observe("123", domain.MessageTypeNodeInfo, 0, start.Add(3*time.Hour))
+	assert.Equal(t, 3, nodeInfo.Expected)
+}
+
+func TestDeliveryTracker_Window(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{
+		Enabled:   true,
+		Intervals: map[string]time.Duration{domain.TelemetryTypeDevice: 30 * time.Minute},
+		Window:    6 * time.Hour,
+	}, 24*time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _ = tracker.observe("123", domain.TelemetryTypeDevice, 0, start)
+	lossy, _ := tracker.observe("123", domain.TelemetryTypeDevice, 0, start.Add(2*time.Hour))
+	assert.InDelta(t, 2.0/5.0, lossy.Ratio, 0.001)
+
+	// после окна старые потери в долю не входят
+	at := start.Add(2 * time.Hour)
+	var stats domain.DeliveryStats
+	for i := 0; i < 13; i++ {
+		at = at.Add(30 * time.Minute)
+		stats, _ = tracker.observe("123", domain.TelemetryTypeDevice, 0, at)
+	}
+	assert.InDelta(t, 1.0, stats.Ratio, 0.001)
+}
+
+func TestDeliveryTracker_TTL(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{
+		Enabled:   true,
+		Intervals: map[string]time.Duration{domain.TelemetryTypeDevice: 30 * time.Minute},
+	}, time.Hour)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _ = tracker.observe("123", domain.TelemetryTypeDevice, 0, start)
+	_, _ = tracker.observe("456", domain.TelemetryTypeDevice, 0, start)
+	lossy, _ := tracker.observe("456", domain.TelemetryTypeDevice, 0, start.Add(time.Hour))
+	assert.Equal(t, 2, lossy.Expected)
+	assert.Len(t, tracker.nodes, 2)
+
+	// суммы окна совпадают с выборками
+	node := tracker.nodes["456"]
+	var expected, received int
+	for _, s := range node.samples {
+		expected += s.expected
+		received += s.received
+	}
+	assert.Equal(t, 3, expected)
+	assert.Equal(t, expected, node.expected)
+	assert.Equal(t, received, node.received)
+
+	// нода 123 молчит дольше TTL: её состояние удалено, как и метрики
+	_, _ = tracker.observe("456", domain.TelemetryTypeDevice, 0, start.Add(90*time.Minute))
+	assert.Len(t, tracker.nodes, 1)
+	fresh, ok := tracker.observe("123", domain.TelemetryTypeDevice, 0, start.Add(3*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, domain.DeliveryStats{NodeID: "123", Stream: domain.TelemetryTypeDevice, Expected: 1, Received: 1, Ratio: 1}, fresh)
+}
+
+func TestDeliveryTracker_UntrackedStream(t *testing.T) {
+	t.Parallel()
+	tracker := newDeliveryTracker(domain.PacketLossConfig{Enabled: true}, 0)
+
+	_, ok := tracker.observe("123", domain.MessageTypeText, 0, time.Now())
+	assert.False(t, ok)
+	_, ok = tracker.observe("123", domain.MessageTypeNodeInfo, 0, time.Now())
+	assert.True(t, ok, "default intervals include nodeinfo")
+
+	var disabled *deliveryTracker
+	_, ok = disabled.observe("123", domain.TelemetryTypeDevice, 0, time.Now())
+	assert.False(t, ok)
+}
+
+func TestMeshtasticProcessor_PacketLoss(t *testing.T) {
+	t.Parallel()
+	mockCollector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(mockCollector, &mocks.MockAlertSender{}, ProcessorOptions{
+		PacketLoss: domain.PacketLossConfig{Enabled: true},
+	})
+
+	payloads := [][]byte{
+		[]byte(`{"from": 123456789, "type": "telemetry", "payload": {"battery_level": 80}}`),
+		[]byte(`{"from": 123456789, "type": "telemetry", "payload": {"temperature": 21.5}}`),
+		[]byte(`{"from": 123456789, "type": "text", "payload": {"text": "hi"}}`),
+		[]byte(`{"from": 123456789, "type": "neighborinfo", "payload": {"node_broadcast_interval_secs": 900}}`),
+	}
+	for _, payload := range payloads {
+		require.NoError(t, processor.ProcessMessage(context.Background(), "msh/test", payload))
+	}
+
+	require.Len(t, mockCollector.DeliveryStats, 3)
+	assert.Equal(t, domain.TelemetryTypeDevice, mockCollector.DeliveryStats[0].Stream)
+	assert.Equal(t, domain.TelemetryTypeEnvironment, mockCollector.DeliveryStats[1].Stream)
+	assert.Equal(t, domain.MessageTypeNeighborInfo, mockCollector.DeliveryStats[2].Stream, "interval advertised by the node")
+}
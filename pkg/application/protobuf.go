@@ -0,0 +1,156 @@
+package application
+
+import (
+	"strconv"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/errors"
+	"meshtastic-exporter/pkg/meshpb"
+	"meshtastic-exporter/pkg/validator"
+)
+
+// portNumMessageTypes сопоставляет portnum protobuf пакета с типом JSON сообщения.
+var portNumMessageTypes = map[meshpb.PortNum]string{
+	meshpb.PortNumTextMessage:     domain.MessageTypeText,
+	meshpb.PortNumPosition:        domain.MessageTypePosition,
+	meshpb.PortNumNodeInfo:        domain.MessageTypeNodeInfo,
+	meshpb.PortNumWaypoint:        domain.MessageTypeWaypoint,
+	meshpb.PortNumTelemetry:       domain.MessageTypeTelemetry,
+	meshpb.PortNumNeighborInfo:    domain.MessageTypeNeighborInfo,
+	meshpb.PortNumMapReport:       domain.MessageTypeMapReport,
+	meshpb.PortNumTraceroute:      domain.MessageTypeTraceroute,
+	meshpb.PortNumRouting:         domain.MessageTypeRouting,
+	meshpb.PortNumDetectionSensor: domain.MessageTypeDetection,
+	meshpb.PortNumPaxcounter:      domain.MessageTypePaxcounter,
+	meshpb.PortNumRangeTest:       domain.MessageTypeRangeTest,
+}
+
+func (p *MeshtasticProcessor) isProtobufTopic(topic string) bool {
+	if p.mapPattern != "" && validator.MatchesMQTTPattern(topic, p.mapPattern) {
+		return true
+	}
+	return p.protobufPattern != "" && validator.MatchesMQTTPattern(topic, p.protobufPattern)
+}
+
+func (p *MeshtasticProcessor) processProtobufMessage(topic string, payload []byte) error {
+	if err := validator.ValidateTopicName(topic); err != nil {
+		p.logger.Warn().Err(err).Str("topic", topic).Msg("invalid topic")
+		return errors.NewValidationError("invalid topic", err)
+	}
+	if err := validator.ValidateProtobufMessage(payload); err != nil {
+		p.logger.Warn().Err(err).Str("topic", topic).Msg("invalid message")
+		return errors.NewValidationError("invalid message", err)
+	}
+
+	envelope, err := meshpb.UnmarshalServiceEnvelope(payload)
+	if err != nil {
+		p.logger.Warn().Err(err).Str("topic", topic).Msg("invalid protobuf envelope")
+		return errors.NewValidationError("invalid protobuf envelope", err)
+	}
+	if envelope.Packet == nil {
+		return errors.NewValidationError("envelope without packet", nil)
+	}
+
+	p.logEnvelopeIfEnabled(topic, envelope)
+
+	packet := envelope.Packet
+	if packet.Decoded == nil {
+		data, err := p.keyring.Decrypt(envelope.ChannelID, packet)
+		if err != nil {
+			p.logger.Debug().Err(err).Str("topic", topic).Uint32("from", packet.From).Msg("undecryptable packet")
+			p.packets.UpdateUndecryptableCounter(channelLabel(envelope))
+			return nil
+		}
+		packet.Decoded = data
+	}
+
+	msg, err := p.convertMeshPacket(packet)
+	if err != nil {
+		p.logger.Warn().Err(err).Str("topic", topic).Uint32("from", packet.From).Msg("failed to decode payload")
+		return err
+	}
+	msg.GatewayID = envelope.GatewayID
+	msg.Channel = envelope.ChannelID
+	p.applyTopicInfo(&msg, topic)
+
+	return p.handleMessage(msg)
+}
+
+// channelLabel имя канала из envelope, для неизвестных каналов — hash из пакета.
+func channelLabel(envelope *meshpb.ServiceEnvelope) string {
+	if envelope.ChannelID != "" {
+		return envelope.ChannelID
+	}
+	return strconv.FormatUint(uint64(envelope.Packet.Channel), 10)
+}
+
+func (p *MeshtasticProcessor) logEnvelopeIfEnabled(topic string, envelope *meshpb.ServiceEnvelope) {
+	if p.logAllMessages && validator.MatchesMQTTPattern(topic, p.topicPattern) {
+		packet := envelope.Packet
+		event := p.logger.Debug().Str("topic", topic).
+			Str("gateway", envelope.GatewayID).
+			Uint32("from", packet.From).
+			Uint32("id", packet.ID)
+		if packet.Decoded != nil {
+			event = event.Uint32("portnum", uint32(packet.Decoded.PortNum))
+		}
+		event.Msg("received")
+	}
+}
+
+// convertMeshPacket приводит protobuf пакет к тому же виду, что и JSON сообщение.
+func (p *MeshtasticProcessor) convertMeshPacket(packet *meshpb.MeshPacket) (domain.MeshtasticMessage, error) {
+	msg := domain.MeshtasticMessage{
+		From:      packet.From,
+		To:        packet.To,
+		ID:        packet.ID,
+		Type:      domain.MessageTypeUnsupported,
+		RxTime:    packet.RxTime,
+		RequestID: packet.Decoded.RequestID,
+	}
+
+	if packet.RxRSSI != 0 {
+		rssi := float64(packet.RxRSSI)
+		msg.RSSI = &rssi
+	}
+	if packet.RxSNR != 0 {
+		snr := packet.RxSNR
+		msg.SNR = &snr
+	}
+
+	if packet.HopStart != 0 {
+		hopStart, hopLimit := packet.HopStart, packet.HopLimit
+		msg.HopStart = &hopStart
+		msg.HopLimit = &hopLimit
+	}
+	if packet.RelayNode != 0 {
+		relayNode := packet.RelayNode
+		msg.RelayNode = &relayNode
+	}
+
+	return p.decodeData(msg, packet.Decoded)
+}
+
+// decodeData тип сообщения и payload по portnum. Порты из реестра обработчиков
+// не разбираются, их payload доступен только как RawPayload.
+func (p *MeshtasticProcessor) decodeData(msg domain.MeshtasticMessage, data *meshpb.Data) (domain.MeshtasticMessage, error) {
+	msg.RawPayload = data.Payload
+	if msgType, ok := p.handlers.portMessageType(data.PortNum); ok {
+		msg.Type = msgType
+		return msg, nil
+	}
+
+	msgType, ok := portNumMessageTypes[data.PortNum]
+	if !ok {
+		return msg, nil
+	}
+	msg.Type = msgType
+
+	payload, err := meshpb.DecodePayload(data.PortNum, data.Payload)
+	if err != nil {
+		return msg, errors.NewProcessingError("protobuf payload decoding failed", err)
+	}
+	msg.Payload = payload
+
+	return msg, nil
+}
@@ -0,0 +1,376 @@
+package application
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/meshpb"
+	"meshtastic-exporter/pkg/mocks"
+)
+
+const protobufTopic = "msh/EU_868/2/e/LongFast/!f992bd54"
+
+func newProtobufProcessor(collector domain.MetricsCollector) *MeshtasticProcessor {
+	return NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+	})
+}
+
+func envelopeFor(from uint32, port meshpb.PortNum, payload []byte) []byte {
+	envelope := meshpb.ServiceEnvelope{
+		Packet: &meshpb.MeshPacket{
+			From:      from,
+			ID:        1,
+			RxRSSI:    -90,
+			RxSNR:     7.5,
+			HopStart:  3,
+			HopLimit:  2,
+			RelayNode: 0xd4,
+			Decoded: &meshpb.Data{
+				PortNum: port,
+				Payload: payload,
+			},
+		},
+		ChannelID: "LongFast",
+		GatewayID: "!f992bd54",
+	}
+	return envelope.Marshal()
+}
+
+func TestMeshtasticProcessor_ProtobufTelemetry(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	var device []byte
+	device = protowire.AppendTag(device, 1, protowire.VarintType)
+	device = protowire.AppendVarint(device, 77)
+	device = protowire.AppendTag(device, 2, protowire.Fixed32Type)
+	device = protowire.AppendFixed32(device, math.Float32bits(3.95))
+	var telemetry []byte
+	telemetry = protowire.AppendTag(telemetry, 2, protowire.BytesType)
+	telemetry = protowire.AppendBytes(telemetry, device)
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumTelemetry, telemetry))
+
+	require.NoError(t, err)
+	require.Len(t, collector.TelemetryData, 1)
+	data := collector.TelemetryData[0]
+	assert.Equal(t, "123456789", data.NodeID)
+	assert.Equal(t, domain.TelemetryTypeDevice, data.Type)
+	assert.Equal(t, 77.0, *data.BatteryLevel)
+	assert.Equal(t, 3.95, *data.Voltage)
+	assert.Equal(t, -90.0, *data.RSSI)
+	assert.Equal(t, 7.5, *data.SNR)
+	assert.Equal(t, map[string]int{"123456789": 1}, collector.PacketHops)
+	assert.Equal(t, []string{"0xd4"}, collector.RelayNodes)
+	require.Len(t, collector.Receptions, 1)
+	assert.Equal(t, "!f992bd54", collector.Receptions[0].GatewayID)
+	assert.Equal(t, -90.0, *collector.Receptions[0].RSSI)
+}
+
+func TestMeshtasticProcessor_ProtobufNodeInfo(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	var user []byte
+	user = protowire.AppendTag(user, 2, protowire.BytesType)
+	user = protowire.AppendString(user, "Rooftop")
+	user = protowire.AppendTag(user, 5, protowire.VarintType)
+	user = protowire.AppendVarint(user, 43)
+	user = protowire.AppendTag(user, 7, protowire.VarintType)
+	user = protowire.AppendVarint(user, 2)
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(987654321, meshpb.PortNumNodeInfo, user))
+
+	require.NoError(t, err)
+	infos := collector.GetNodeInfos()
+	require.Len(t, infos, 1)
+	assert.Equal(t, "Rooftop", infos[0].LongName)
+	assert.Equal(t, "router", infos[0].Role)
+	assert.Equal(t, "43", infos[0].Hardware)
+	assert.Equal(t, "HELTEC_V3", infos[0].HWModel)
+}
+
+func TestMeshtasticProcessor_ProtobufMapReport(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		MapReportPattern: domain.DefaultMapReportPattern,
+	})
+
+	var report []byte
+	report = protowire.AppendTag(report, 5, protowire.BytesType)
+	report = protowire.AppendString(report, "2.5.15.79da236")
+	report = protowire.AppendTag(report, 6, protowire.VarintType)
+	report = protowire.AppendVarint(report, 9)
+	report = protowire.AppendTag(report, 7, protowire.VarintType)
+	report = protowire.AppendVarint(report, 4)
+	report = protowire.AppendTag(report, 8, protowire.VarintType)
+	report = protowire.AppendVarint(report, 1)
+	report = protowire.AppendTag(report, 9, protowire.Fixed32Type)
+	report = protowire.AppendFixed32(report, 557500000)
+	report = protowire.AppendTag(report, 10, protowire.Fixed32Type)
+	report = protowire.AppendFixed32(report, 376200000)
+	report = protowire.AppendTag(report, 12, protowire.VarintType)
+	report = protowire.AppendVarint(report, 13)
+	report = protowire.AppendTag(report, 13, protowire.VarintType)
+	report = protowire.AppendVarint(report, 17)
+
+	err := processor.ProcessMessage(context.Background(), "msh/RU/2/map/", envelopeFor(123456789, meshpb.PortNumMapReport, report))
+
+	require.NoError(t, err)
+	require.Len(t, collector.MapReports, 1)
+	got := collector.MapReports[0]
+	assert.Equal(t, "123456789", got.NodeID)
+	assert.Equal(t, "2.5.15.79da236", got.FirmwareVersion)
+	assert.Equal(t, "RU", got.Region)
+	assert.Equal(t, "MEDIUM_FAST", got.ModemPreset)
+	assert.True(t, got.HasDefaultChannel)
+	assert.Equal(t, int32(17), *got.OnlineLocalNodes)
+	assert.InDelta(t, 55.75, *got.Position.Latitude, 1e-9)
+	assert.InDelta(t, 37.62, *got.Position.Longitude, 1e-9)
+	assert.Equal(t, int32(13), *got.Position.PrecisionBits)
+	assert.Nil(t, got.Position.Altitude)
+}
+
+func TestMeshtasticProcessor_MapReportDisabled(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	err := processor.ProcessMessage(context.Background(), "msh/RU/2/map/", envelopeFor(123456789, meshpb.PortNumMapReport, nil))
+
+	require.NoError(t, err)
+	assert.Empty(t, collector.MapReports)
+}
+
+func TestMeshtasticProcessor_ProtobufUndecryptablePacket(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	envelope := meshpb.ServiceEnvelope{
+		Packet:    &meshpb.MeshPacket{From: 123456789, ID: 1, Encrypted: []byte{0x01, 0x02, 0x03}},
+		ChannelID: "Private",
+	}
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelope.Marshal())
+
+	require.NoError(t, err)
+	assert.False(t, collector.UpdateNodeLastSeenCalled)
+	assert.Equal(t, []string{"Private"}, collector.UndecryptableChannels)
+}
+
+func TestMeshtasticProcessor_ProtobufEncryptedPacket(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		ChannelKeys:     map[string]string{domain.DefaultChannelName: domain.DefaultChannelKey},
+	})
+
+	key, err := meshpb.ParsePSK(domain.DefaultChannelKey)
+	require.NoError(t, err)
+	data := meshpb.Data{PortNum: meshpb.PortNumTextMessage, Payload: []byte("hello mesh")}
+	encrypted, err := meshpb.XORKeyStream(key, 0x1234, 123456789, data.Marshal())
+	require.NoError(t, err)
+
+	envelope := meshpb.ServiceEnvelope{
+		Packet: &meshpb.MeshPacket{
+			From:      123456789,
+			ID:        0x1234,
+			Channel:   meshpb.ChannelHash(domain.DefaultChannelName, key),
+			Encrypted: encrypted,
+		},
+		ChannelID: domain.DefaultChannelName,
+	}
+
+	err = processor.ProcessMessage(context.Background(), protobufTopic, envelope.Marshal())
+
+	require.NoError(t, err)
+	assert.True(t, collector.UpdateNodeLastSeenCalled)
+	assert.Empty(t, collector.UndecryptableChannels)
+}
+
+func TestMeshtasticProcessor_ProtobufWrongKeyPacket(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		ChannelKeys:     map[string]string{domain.DefaultChannelName: domain.DefaultChannelKey},
+	})
+
+	key, err := meshpb.ParsePSK(domain.DefaultChannelKey)
+	require.NoError(t, err)
+	// пакет другой сети с тем же hash канала: после расшифровки шум
+	encrypted, err := meshpb.XORKeyStream(key, 0x1234, 123456789, []byte{0x08, 0x43, 0x12, 0x04, 0x01, 0x02})
+	require.NoError(t, err)
+
+	envelope := meshpb.ServiceEnvelope{
+		Packet: &meshpb.MeshPacket{
+			From:      123456789,
+			ID:        0x1234,
+			Channel:   meshpb.ChannelHash(domain.DefaultChannelName, key),
+			Encrypted: encrypted,
+		},
+		ChannelID: domain.DefaultChannelName,
+	}
+
+	err = processor.ProcessMessage(context.Background(), protobufTopic, envelope.Marshal())
+
+	require.NoError(t, err)
+	assert.False(t, collector.UpdateNodeLastSeenCalled)
+	assert.Empty(t, collector.TelemetryData)
+	assert.Equal(t, []string{domain.DefaultChannelName}, collector.UndecryptableChannels)
+}
+
+func TestMeshtasticProcessor_ProtobufInvalidEnvelope(t *testing.T) {
+	t.Parallel()
+	processor := newProtobufProcessor(&mocks.MockMetricsCollector{})
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, []byte{0x0a, 0x20, 0x01})
+	require.Error(t, err)
+
+	err = processor.ProcessMessage(context.Background(), protobufTopic, nil)
+	require.Error(t, err)
+}
+
+func TestMeshtasticProcessor_ProtobufDisabled(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessor(collector, &mocks.MockAlertSender{}, false, "")
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumTelemetry, nil))
+
+	require.NoError(t, err)
+	assert.False(t, collector.CollectTelemetryCalled)
+}
+
+func routeDiscovery(route []uint32, snrTowards []int64, routeBack []uint32, snrBack []int64) []byte {
+	appendNodes := func(b []byte, num protowire.Number, nodes []uint32) []byte {
+		var packed []byte
+		for _, node := range nodes {
+			packed = protowire.AppendFixed32(packed, node)
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, packed)
+	}
+	appendSNR := func(b []byte, num protowire.Number, values []int64) []byte {
+		var packed []byte
+		for _, value := range values {
+			packed = protowire.AppendVarint(packed, uint64(value))
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, packed)
+	}
+
+	var discovery []byte
+	discovery = appendNodes(discovery, 1, route)
+	discovery = appendSNR(discovery, 2, snrTowards)
+	discovery = appendNodes(discovery, 3, routeBack)
+	return appendSNR(discovery, 4, snrBack)
+}
+
+func TestMeshtasticProcessor_ProtobufTraceroute(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := NewMeshtasticProcessorWithOptions(collector, &mocks.MockAlertSender{}, ProcessorOptions{
+		ProtobufPattern: domain.DefaultProtobufPattern,
+		NodeIDFormat:    domain.NodeIDFormatHex,
+	})
+
+	// 0x0a -> 0x0b -> 0x0c, обратно 0x0c -> 0x0d -> 0x0a; SNR второго хопа неизвестен
+	payload := routeDiscovery([]uint32{0x0b}, []int64{25, domain.TracerouteSNRUnknown}, []uint32{0x0d}, []int64{-18, 40})
+	envelope := meshpb.ServiceEnvelope{
+		Packet: &meshpb.MeshPacket{
+			From:    0x0c,
+			To:      0x0a,
+			ID:      7,
+			Decoded: &meshpb.Data{PortNum: meshpb.PortNumTraceroute, Payload: payload, RequestID: 6},
+		},
+		GatewayID: "!0000000a",
+	}
+
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelope.Marshal())
+
+	require.NoError(t, err)
+	require.Len(t, collector.Traceroutes, 1)
+	tr := collector.Traceroutes[0]
+	assert.Equal(t, "!0000000a", tr.Origin)
+	assert.Equal(t, "!0000000c", tr.Destination)
+	assert.Equal(t, []string{"!0000000a", "!0000000b", "!0000000c"}, tr.Route)
+	assert.Equal(t, []string{"!0000000c", "!0000000d", "!0000000a"}, tr.RouteBack)
+	assert.Equal(t, []domain.TracerouteHop{
+		{From: "!0000000a", To: "!0000000b", Direction: domain.TracerouteTowards, SNR: 6.25},
+		{From: "!0000000c", To: "!0000000d", Direction: domain.TracerouteBack, SNR: -4.5},
+		{From: "!0000000d", To: "!0000000a", Direction: domain.TracerouteBack, SNR: 10},
+	}, tr.Hops)
+}
+
+func TestMeshtasticProcessor_TracerouteRequestOnlyCounted(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	payload := routeDiscovery([]uint32{0x0b}, []int64{25}, nil, nil)
+	err := processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(0x0a, meshpb.PortNumTraceroute, payload))
+
+	require.NoError(t, err)
+	assert.Empty(t, collector.Traceroutes)
+}
+
+func TestMeshtasticProcessor_ProtobufRouting(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	routingPacket := func(errorReason uint64, requestID uint32) []byte {
+		var routing []byte
+		if errorReason != 0 {
+			routing = protowire.AppendTag(routing, 3, protowire.VarintType)
+			routing = protowire.AppendVarint(routing, errorReason)
+		}
+		envelope := meshpb.ServiceEnvelope{
+			Packet: &meshpb.MeshPacket{
+				From:    123456789,
+				ID:      uint32(errorReason) + 100,
+				Decoded: &meshpb.Data{PortNum: meshpb.PortNumRouting, Payload: routing, RequestID: requestID},
+			},
+		}
+		return envelope.Marshal()
+	}
+
+	for _, payload := range [][]byte{routingPacket(0, 55), routingPacket(9, 56), routingPacket(0, 0)} {
+		require.NoError(t, processor.ProcessMessage(context.Background(), protobufTopic, payload))
+	}
+
+	require.Len(t, collector.RoutingResults, 2)
+	assert.Equal(t, domain.RoutingResult{NodeID: "123456789", Reason: domain.RoutingErrorNone, RequestID: 55, Timestamp: collector.RoutingResults[0].Timestamp}, collector.RoutingResults[0])
+	assert.Equal(t, "DUTY_CYCLE_LIMIT", collector.RoutingResults[1].Reason)
+	assert.Equal(t, uint32(56), collector.RoutingResults[1].RequestID)
+}
+
+func TestMeshtasticProcessor_ProtobufRangeTest(t *testing.T) {
+	t.Parallel()
+	collector := &mocks.MockMetricsCollector{}
+	processor := newProtobufProcessor(collector)
+
+	require.NoError(t, processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456789, meshpb.PortNumRangeTest, []byte("seq 17"))))
+	require.NoError(t, processor.ProcessMessage(context.Background(), protobufTopic, envelopeFor(123456788, meshpb.PortNumRangeTest, []byte("hello"))))
+
+	require.Len(t, collector.RangeTestPackets, 1)
+	packet := collector.RangeTestPackets[0]
+	assert.Equal(t, "123456789", packet.NodeID)
+	assert.Equal(t, "!f992bd54", packet.GatewayID)
+	assert.Equal(t, uint32(17), packet.Sequence)
+	require.NotNil(t, packet.RSSI)
+	assert.InDelta(t, -90, *packet.RSSI, 0.001)
+}
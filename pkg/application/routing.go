@@ -0,0 +1,22 @@
+package application
+
+import (
+	"meshtastic-exporter/pkg/domain"
+)
+
+// processRouting ACK или ошибка доставки. Пакеты без error_reason и request_id —
+// это route_request/route_reply, их только считаем.
+func (p *MeshtasticProcessor) processRouting(nodeID string, msg domain.MeshtasticMessage) error {
+	reason := enumName(msg.Payload, "error_reason", domain.GetRoutingErrorName)
+	if reason == domain.RoutingErrorNone && msg.RequestID == 0 {
+		p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeRouting)
+		return nil
+	}
+
+	return p.modules.CollectRoutingResult(domain.RoutingResult{
+		NodeID:    nodeID,
+		Reason:    reason,
+		RequestID: msg.RequestID,
+		Timestamp: msg.ReceivedAt,
+	})
+}
@@ -0,0 +1,83 @@
+package application
+
+import (
+	"math"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+// processTraceroute разбирает ответ на трассировку (RouteDiscovery). Ответ идёт
+// от Destination к Origin, поэтому отправитель пакета — Destination, а to — Origin.
+// Запросы в пути и JSON сообщения (прошивка заменяет номера нод именами) только считаются.
+func (p *MeshtasticProcessor) processTraceroute(nodeID string, msg domain.MeshtasticMessage) error {
+	if msg.RequestID == 0 || msg.To == 0 || msg.To == domain.LoRaBroadcastNodeID {
+		p.collector.UpdateMessageCounter(nodeID, domain.MessageTypeTraceroute)
+		return nil
+	}
+
+	origin := p.formatNodeID(msg.To)
+	tr := domain.Traceroute{
+		Origin:      origin,
+		Destination: nodeID,
+		Timestamp:   msg.ReceivedAt,
+	}
+
+	tr.Route = append(append([]string{origin}, p.nodeList(msg.Payload, "route")...), nodeID)
+	tr.Hops = tracerouteHops(tr.Route, snrList(msg.Payload, "snr_towards"), domain.TracerouteTowards)
+
+	// обратный путь пишут прошивки 2.3+; ответ дошёл до Origin, когда SNR записан на каждом хопе
+	if _, ok := msg.Payload["snr_back"]; ok || msg.Payload["route_back"] != nil {
+		back := append([]string{nodeID}, p.nodeList(msg.Payload, "route_back")...)
+		snr := snrList(msg.Payload, "snr_back")
+		if len(snr) >= len(back) {
+			back = append(back, origin)
+		}
+		tr.RouteBack = back
+		tr.Hops = append(tr.Hops, tracerouteHops(back, snr, domain.TracerouteBack)...)
+	}
+
+	return p.modules.CollectTraceroute(tr)
+}
+
+func (p *MeshtasticProcessor) nodeList(payload map[string]interface{}, key string) []string {
+	entries, _ := payload[key].([]interface{})
+	nodes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if num, ok := entry.(float64); ok && num > 0 && num <= math.MaxUint32 {
+			nodes = append(nodes, p.formatNodeID(uint32(num)))
+		}
+	}
+	return nodes
+}
+
+// snrList SNR хопов в dB, неизвестные значения — NaN.
+func snrList(payload map[string]interface{}, key string) []float64 {
+	entries, _ := payload[key].([]interface{})
+	values := make([]float64, 0, len(entries))
+	for _, entry := range entries {
+		raw, ok := entry.(float64)
+		if !ok || raw == domain.TracerouteSNRUnknown {
+			values = append(values, math.NaN())
+			continue
+		}
+		values = append(values, raw/domain.TracerouteSNRScale)
+	}
+	return values
+}
+
+// tracerouteHops хопы пути path, snr[i] измерен нодой path[i+1].
+func tracerouteHops(path []string, snr []float64, direction string) []domain.TracerouteHop {
+	var hops []domain.TracerouteHop
+	for i := 0; i+1 < len(path) && i < len(snr); i++ {
+		if math.IsNaN(snr[i]) {
+			continue
+		}
+		hops = append(hops, domain.TracerouteHop{
+			From:      path[i],
+			To:        path[i+1],
+			Direction: direction,
+			SNR:       snr[i],
+		})
+	}
+	return hops
+}
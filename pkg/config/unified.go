@@ -2,8 +2,6 @@ package config
 
 import (
 	"os"
-	"strconv"
-	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -20,6 +18,18 @@ type AlertRoute struct {
 	ShowOnSender bool     `yaml:"show_on_sender"`
 }
 
+// MetricMapping поле сообщения, которое экспортируется как метрика без изменения кода.
+type MetricMapping struct {
+	MessageType string            `yaml:"message_type"`
+	Path        string            `yaml:"path"`
+	Metric      string            `yaml:"metric"`
+	Type        string            `yaml:"type"`
+	Unit        string            `yaml:"unit"`
+	Help        string            `yaml:"help"`
+	Labels      map[string]string `yaml:"labels"`
+	Scale       float64           `yaml:"scale"`
+}
+
 type UnifiedConfig struct {
 	Logging struct {
 		Level string `yaml:"level"`
@@ -64,10 +74,30 @@ type UnifiedConfig struct {
 			MetricsTTL string `yaml:"metrics_ttl"`
 			KeepAlive  string `yaml:"keep_alive"`
 			Topic      struct {
-				Pattern        string `yaml:"pattern"`
-				LogAllMessages bool   `yaml:"log_all_messages"`
+				Pattern         string `yaml:"pattern"`
+				ProtobufPattern string `yaml:"protobuf_pattern"`
+				MapPattern      string `yaml:"map_pattern"`
+				Template        string `yaml:"template"`
+				LogAllMessages  bool   `yaml:"log_all_messages"`
 			} `yaml:"topic"`
-			StateFile string `yaml:"state_file"`
+			ChannelKeys    map[string]string `yaml:"channel_keys"`
+			DedupWindow    string            `yaml:"dedup_window"`
+			NodeIDFormat   string            `yaml:"node_id_format"`
+			DerivedMetrics bool              `yaml:"derived_metrics"`
+			StateFile      string            `yaml:"state_file"`
+			Battery        struct {
+				Enabled   bool                 `yaml:"enabled"`
+				Chemistry string               `yaml:"chemistry"`
+				Window    string               `yaml:"window"`
+				Nodes     map[string]string    `yaml:"nodes"`
+				Curves    map[string][]float64 `yaml:"curves"`
+			} `yaml:"battery"`
+			PacketLoss struct {
+				Enabled   bool              `yaml:"enabled"`
+				Intervals map[string]string `yaml:"intervals"`
+				Window    string            `yaml:"window"`
+			} `yaml:"packet_loss"`
+			Mappings []MetricMapping `yaml:"mappings"`
 		} `yaml:"prometheus"`
 		AlertManager struct {
 			Path       string `yaml:"path"`
@@ -116,7 +146,15 @@ func setDefaults(config *UnifiedConfig) {
 	config.Hook.Prometheus.Path = "/metrics"
 	config.Hook.Prometheus.MetricsTTL = "30m"
 	config.Hook.Prometheus.Topic.Pattern = domain.DefaultTopicPrefix
+	config.Hook.Prometheus.Topic.ProtobufPattern = domain.DefaultProtobufPattern
+	config.Hook.Prometheus.Topic.MapPattern = domain.DefaultMapReportPattern
+	config.Hook.Prometheus.Topic.Template = domain.DefaultTopicTemplate
 	config.Hook.Prometheus.Topic.LogAllMessages = false
+	config.Hook.Prometheus.DedupWindow = "1m"
+	config.Hook.Prometheus.NodeIDFormat = domain.NodeIDFormatDecimal
+	config.Hook.Prometheus.Battery.Chemistry = domain.BatteryChemistryLiIon
+	config.Hook.Prometheus.Battery.Window = "6h"
+	config.Hook.Prometheus.ChannelKeys = map[string]string{domain.DefaultChannelName: domain.DefaultChannelKey}
 	config.Hook.AlertManager.Path = domain.DefaultAlertsPath
 }
 
@@ -183,17 +221,91 @@ func buildPrometheusConfig(config *UnifiedConfig) adapters.PrometheusConfigAdapt
 	if err != nil {
 		metricsTTL = domain.DefaultMetricsTTL
 	}
+	dedupWindow, err := time.ParseDuration(config.Hook.Prometheus.DedupWindow)
+	if err != nil {
+		dedupWindow = domain.DefaultDedupWindow
+	}
 
 	return adapters.PrometheusConfigAdapter{
-		Listen:         config.Hook.Listen,
-		Path:           config.Hook.Prometheus.Path,
-		MetricsTTL:     metricsTTL,
-		TopicPattern:   config.Hook.Prometheus.Topic.Pattern,
-		LogAllMessages: config.Hook.Prometheus.Topic.LogAllMessages,
-		StateFile:      config.Hook.Prometheus.StateFile,
+		Listen:           config.Hook.Listen,
+		Path:             config.Hook.Prometheus.Path,
+		MetricsTTL:       metricsTTL,
+		TopicPattern:     config.Hook.Prometheus.Topic.Pattern,
+		ProtobufPattern:  config.Hook.Prometheus.Topic.ProtobufPattern,
+		MapReportPattern: config.Hook.Prometheus.Topic.MapPattern,
+		TopicTemplate:    config.Hook.Prometheus.Topic.Template,
+		ChannelKeys:      config.Hook.Prometheus.ChannelKeys,
+		DedupWindow:      dedupWindow,
+		NodeIDFormat:     config.Hook.Prometheus.NodeIDFormat,
+		DerivedMetrics:   config.Hook.Prometheus.DerivedMetrics,
+		BatteryModel:     buildBatteryModelConfig(config),
+		PacketLoss:       buildPacketLossConfig(config),
+		Mappings:         buildMetricMappings(config),
+		LogAllMessages:   config.Hook.Prometheus.Topic.LogAllMessages,
+		StateFile:        config.Hook.Prometheus.StateFile,
+	}
+}
+
+func buildBatteryModelConfig(config *UnifiedConfig) domain.BatteryModelConfig {
+	battery := config.Hook.Prometheus.Battery
+	window, err := time.ParseDuration(battery.Window)
+	if err != nil {
+		window = domain.DefaultBatteryWindow
+	}
+
+	curves := make(map[string]domain.BatteryCurve, len(battery.Curves))
+	for name, curve := range battery.Curves {
+		curves[name] = curve
+	}
+
+	return domain.BatteryModelConfig{
+		Enabled:       battery.Enabled,
+		Chemistry:     battery.Chemistry,
+		Window:        window,
+		NodeChemistry: battery.Nodes,
+		Curves:        curves,
 	}
 }
 
+// buildPacketLossConfig некорректный интервал превращается в 0, его отклонит Validate.
+func buildPacketLossConfig(config *UnifiedConfig) domain.PacketLossConfig {
+	packetLoss := config.Hook.Prometheus.PacketLoss
+	intervals := make(map[string]time.Duration, len(packetLoss.Intervals))
+	for stream, value := range packetLoss.Intervals {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			interval = 0
+		}
+		intervals[stream] = interval
+	}
+	window, err := time.ParseDuration(packetLoss.Window)
+	if err != nil {
+		window = domain.DefaultPacketLossWindow
+	}
+	return domain.PacketLossConfig{Enabled: packetLoss.Enabled, Intervals: intervals, Window: window}
+}
+
+func buildMetricMappings(config *UnifiedConfig) []domain.MetricMapping {
+	mappings := make([]domain.MetricMapping, 0, len(config.Hook.Prometheus.Mappings))
+	for _, m := range config.Hook.Prometheus.Mappings {
+		mappingType := m.Type
+		if mappingType == "" {
+			mappingType = domain.MappingTypeGauge
+		}
+		mappings = append(mappings, domain.MetricMapping{
+			MessageType: m.MessageType,
+			Path:        m.Path,
+			Metric:      m.Metric,
+			Type:        mappingType,
+			Unit:        m.Unit,
+			Help:        m.Help,
+			Labels:      m.Labels,
+			Scale:       m.Scale,
+		})
+	}
+	return mappings
+}
+
 func buildAlertManagerConfig(config *UnifiedConfig) adapters.AlertManagerConfigAdapter {
 	routingConfig := adapters.AlertRoutingConfig{
 		Default:  convertAlertRoute(config.Hook.AlertManager.Routing.Default),
@@ -204,7 +316,7 @@ func buildAlertManagerConfig(config *UnifiedConfig) adapters.AlertManagerConfigA
 
 	var fromNodeID uint32
 	if config.Hook.AlertManager.FromNodeID != "" {
-		if nodeID, err := parseNodeID(config.Hook.AlertManager.FromNodeID); err == nil {
+		if nodeID, err := domain.ParseNodeID(config.Hook.AlertManager.FromNodeID); err == nil {
 			fromNodeID = nodeID
 		}
 	}
@@ -265,7 +377,7 @@ func convertAlertRoute(route *AlertRoute) *adapters.AlertRouteConfig {
 
 	var targetNodes []uint32
 	for _, nodeStr := range route.TargetNodes {
-		if nodeID, err := parseNodeID(nodeStr); err == nil {
+		if nodeID, err := domain.ParseNodeID(nodeStr); err == nil {
 			targetNodes = append(targetNodes, nodeID)
 		}
 	}
@@ -276,18 +388,3 @@ func convertAlertRoute(route *AlertRoute) *adapters.AlertRouteConfig {
 		ShowOnSender: route.ShowOnSender,
 	}
 }
-
-func parseNodeID(nodeStr string) (uint32, error) {
-	// Поддержка hex формата (0x prefix или !)
-	if strings.HasPrefix(nodeStr, "0x") || strings.HasPrefix(nodeStr, "0X") {
-		val, err := strconv.ParseUint(nodeStr[2:], 16, 32)
-		return uint32(val), err
-	}
-	if strings.HasPrefix(nodeStr, "!") {
-		val, err := strconv.ParseUint(nodeStr[1:], 16, 32)
-		return uint32(val), err
-	}
-	// Десятичный формат по умолчанию
-	val, err := strconv.ParseUint(nodeStr, 10, 32)
-	return uint32(val), err
-}
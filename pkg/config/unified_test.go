@@ -216,3 +216,239 @@ func TestConvertToAdapter_LogAllMessages(t *testing.T) {
 		t.Error("Expected log_all_messages to be true")
 	}
 }
+
+func TestConvertToAdapter_ProtobufDefaults(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	prometheusConfig := processingConfig(t, adapter)
+	if prometheusConfig.GetProtobufPattern() != domain.DefaultProtobufPattern {
+		t.Errorf("Expected protobuf pattern %s, got %s", domain.DefaultProtobufPattern, prometheusConfig.GetProtobufPattern())
+	}
+	if prometheusConfig.GetMapReportPattern() != domain.DefaultMapReportPattern {
+		t.Errorf("Expected map report pattern %s, got %s", domain.DefaultMapReportPattern, prometheusConfig.GetMapReportPattern())
+	}
+	if key := prometheusConfig.GetChannelKeys()[domain.DefaultChannelName]; key != domain.DefaultChannelKey {
+		t.Errorf("Expected default channel key %s, got %s", domain.DefaultChannelKey, key)
+	}
+	if prometheusConfig.GetDedupWindow() != domain.DefaultDedupWindow {
+		t.Errorf("Expected dedup window %v, got %v", domain.DefaultDedupWindow, prometheusConfig.GetDedupWindow())
+	}
+	if prometheusConfig.GetNodeIDFormat() != domain.NodeIDFormatDecimal {
+		t.Errorf("Expected node id format %s, got %s", domain.NodeIDFormatDecimal, prometheusConfig.GetNodeIDFormat())
+	}
+	if prometheusConfig.GetTopicTemplate() != domain.DefaultTopicTemplate {
+		t.Errorf("Expected topic template %s, got %s", domain.DefaultTopicTemplate, prometheusConfig.GetTopicTemplate())
+	}
+	if err := adapter.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+}
+
+func TestConvertToAdapter_InvalidTopicTemplate(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+	config.Hook.Prometheus.Topic.Template = "msh/{region}/{city}"
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := adapter.Validate(); err == nil {
+		t.Error("Expected validation error for unknown placeholder")
+	}
+}
+
+func TestConvertToAdapter_InvalidNodeIDFormat(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+	config.Hook.Prometheus.NodeIDFormat = "octal"
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := adapter.Validate(); err == nil {
+		t.Error("Expected validation error for unknown node id format")
+	}
+}
+
+func TestConvertToAdapter_BatteryModel(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+	config.Hook.Prometheus.Battery.Enabled = true
+	config.Hook.Prometheus.Battery.Window = "12h"
+	config.Hook.Prometheus.Battery.Nodes = map[string]string{"!f992bd54": "agm"}
+	config.Hook.Prometheus.Battery.Curves = map[string][]float64{"agm": {11.8, 12.2, 12.7}}
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := adapter.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	battery := processingConfig(t, adapter).GetBatteryModel()
+	if !battery.Enabled || battery.Chemistry != domain.BatteryChemistryLiIon || battery.Window != 12*time.Hour {
+		t.Errorf("Unexpected battery config %+v", battery)
+	}
+	if len(battery.Curves["agm"]) != 3 {
+		t.Errorf("Expected custom curve, got %v", battery.Curves)
+	}
+}
+
+func TestConvertToAdapter_InvalidBatteryModel(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		modify func(config *UnifiedConfig)
+	}{
+		{"unknown chemistry", func(config *UnifiedConfig) { config.Hook.Prometheus.Battery.Chemistry = "nicd" }},
+		{"unknown node chemistry", func(config *UnifiedConfig) {
+			config.Hook.Prometheus.Battery.Nodes = map[string]string{"123": "nicd"}
+		}},
+		{"descending curve", func(config *UnifiedConfig) {
+			config.Hook.Prometheus.Battery.Curves = map[string][]float64{"agm": {12.7, 11.8}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := &UnifiedConfig{}
+			setDefaults(config)
+			tt.modify(config)
+
+			adapter, err := convertToAdapter(config)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := adapter.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestConvertToAdapter_PacketLossIntervals(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+	config.Hook.Prometheus.PacketLoss.Enabled = true
+	config.Hook.Prometheus.PacketLoss.Intervals = map[string]string{"device_metrics": "15m"}
+	config.Hook.Prometheus.PacketLoss.Window = "6h"
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := adapter.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	packetLoss := processingConfig(t, adapter).GetPacketLoss()
+	if !packetLoss.Enabled || packetLoss.Intervals["device_metrics"] != 15*time.Minute || packetLoss.Window != 6*time.Hour {
+		t.Errorf("Unexpected packet loss config %+v", packetLoss)
+	}
+
+	config.Hook.Prometheus.PacketLoss.Intervals = map[string]string{"device_metrics": "often"}
+	adapter, _ = convertToAdapter(config)
+	if err := adapter.Validate(); err == nil {
+		t.Error("Expected validation error for invalid interval")
+	}
+}
+
+func TestConvertToAdapter_InvalidChannelKey(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+	config.Hook.Prometheus.ChannelKeys["Private"] = "AAEC"
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := adapter.Validate(); err == nil {
+		t.Error("Expected validation error for invalid channel key")
+	}
+}
+
+func TestConvertToAdapter_MetricMappings(t *testing.T) {
+	t.Parallel()
+	config := &UnifiedConfig{}
+	setDefaults(config)
+	config.Hook.Prometheus.Mappings = []MetricMapping{
+		{MessageType: "telemetry", Path: "soil_conductivity", Metric: "meshtastic_soil_conductivity", Unit: "microsiemens"},
+		{MessageType: "text", Metric: "meshtastic_text_by_channel", Type: "counter", Labels: map[string]string{"channel": "channel"}},
+	}
+
+	adapter, err := convertToAdapter(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := adapter.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	mappings := processingConfig(t, adapter).GetMetricMappings()
+	if len(mappings) != 2 {
+		t.Fatalf("Expected 2 mappings, got %d", len(mappings))
+	}
+	if mappings[0].Type != domain.MappingTypeGauge || mappings[0].MetricName() != "meshtastic_soil_conductivity_microsiemens" {
+		t.Errorf("Unexpected gauge mapping %+v", mappings[0])
+	}
+	if mappings[1].MetricName() != "meshtastic_text_by_channel_total" {
+		t.Errorf("Unexpected counter name %s", mappings[1].MetricName())
+	}
+}
+
+func TestConvertToAdapter_InvalidMetricMappings(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		mapping MetricMapping
+	}{
+		{"no message type", MetricMapping{Path: "a", Metric: "meshtastic_a"}},
+		{"gauge without path", MetricMapping{MessageType: "telemetry", Metric: "meshtastic_a"}},
+		{"unknown type", MetricMapping{MessageType: "telemetry", Path: "a", Metric: "meshtastic_a", Type: "histogram"}},
+		{"invalid metric name", MetricMapping{MessageType: "telemetry", Path: "a", Metric: "meshtastic-a"}},
+		{"node_id label", MetricMapping{MessageType: "telemetry", Path: "a", Metric: "meshtastic_a", Labels: map[string]string{"node_id": "from"}}},
+		{"conflicting declaration", MetricMapping{MessageType: "position", Path: "a", Metric: "meshtastic_soil_conductivity", Type: "counter"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := &UnifiedConfig{}
+			setDefaults(config)
+			config.Hook.Prometheus.Mappings = []MetricMapping{
+				{MessageType: "telemetry", Path: "soil_conductivity", Metric: "meshtastic_soil_conductivity_total", Type: "gauge"},
+				tt.mapping,
+			}
+
+			adapter, err := convertToAdapter(config)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := adapter.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func processingConfig(t *testing.T, adapter domain.Config) domain.PrometheusProcessingConfig {
+	t.Helper()
+	processing, ok := adapter.GetPrometheusConfig().(domain.PrometheusProcessingConfig)
+	if !ok {
+		t.Fatalf("Expected prometheus config to implement PrometheusProcessingConfig")
+	}
+	return processing
+}
@@ -0,0 +1,84 @@
+package domain
+
+import "time"
+
+// Химия батареи для оценки заряда по напряжению
+const (
+	BatteryChemistryLiIon   = "li-ion"
+	BatteryChemistryLiFePO4 = "lifepo4"
+
+	DefaultBatteryWindow = 6 * time.Hour
+
+	// BatteryPoweredLevel battery_level, которым прошивка сообщает о внешнем питании
+	BatteryPoweredLevel = 101
+	// BatterySlopeThreshold изменение заряда (%/ч), меньше которого считаем, что заряд стоит на месте
+	BatterySlopeThreshold = 0.5
+	// BatteryMinSamples минимум замеров напряжения в окне для оценки скорости разряда
+	BatteryMinSamples = 3
+)
+
+// BatteryCurve напряжение ячейки (В) при заряде 0%, ..., 100% с равным шагом.
+type BatteryCurve []float64
+
+// BatteryCurves кривые по умолчанию, те же точки, что в таблице OCV прошивки Meshtastic.
+var BatteryCurves = map[string]BatteryCurve{
+	BatteryChemistryLiIon:   {3.10, 3.30, 3.42, 3.53, 3.63, 3.72, 3.80, 3.89, 3.99, 4.05, 4.19},
+	BatteryChemistryLiFePO4: {3.00, 3.12, 3.20, 3.23, 3.25, 3.26, 3.27, 3.29, 3.32, 3.35, 3.40},
+}
+
+// StateOfCharge заряд в процентах, линейная интерполяция между точками кривой.
+func (c BatteryCurve) StateOfCharge(voltage float64) float64 {
+	if len(c) < 2 {
+		return 0
+	}
+	last := len(c) - 1
+	if voltage <= c[0] {
+		return 0
+	}
+	if voltage >= c[last] {
+		return 100
+	}
+
+	step := 100 / float64(last)
+	for i := 1; i <= last; i++ {
+		if voltage < c[i] {
+			fraction := (voltage - c[i-1]) / (c[i] - c[i-1])
+			return (float64(i-1) + fraction) * step
+		}
+	}
+	return 100
+}
+
+// Valid кривая должна содержать хотя бы две точки и строго возрастать:
+// при равных напряжениях интерполяция неоднозначна.
+func (c BatteryCurve) Valid() bool {
+	if len(c) < 2 {
+		return false
+	}
+	for i := 1; i < len(c); i++ {
+		if c[i] <= c[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// BatteryModelConfig настройки оценки заряда и времени до разряда.
+type BatteryModelConfig struct {
+	Enabled   bool
+	Chemistry string
+	Window    time.Duration
+	// NodeChemistry химия для отдельных нод: node_id в любом формате -> имя кривой
+	NodeChemistry map[string]string
+	// Curves дополнительные кривые, переопределяют встроенные с тем же именем
+	Curves map[string]BatteryCurve
+}
+
+// BatteryEstimate результат модели батареи для одного замера.
+type BatteryEstimate struct {
+	StateOfCharge float64
+	ExternalPower bool
+	Charging      bool
+	// TimeToEmpty nil, если нода не разряжается или истории пока мало
+	TimeToEmpty *time.Duration
+}
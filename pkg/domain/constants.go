@@ -16,16 +16,147 @@ const (
 	MetricUptime        = "meshtastic_uptime_seconds"
 	MetricNodeLastSeen  = "meshtastic_node_last_seen_timestamp"
 	MetricNodeInfo      = "meshtastic_node_info"
+	MetricNodesByHW     = "meshtastic_nodes_by_hardware"
 	MetricRSSI          = "meshtastic_rssi_dbm"
 	MetricSNR           = "meshtastic_snr_db"
 	MetricMessagesTotal = "meshtastic_messages_total"
 	MetricExporterInfo  = "meshtastic_exporter_info"
 
+	MetricUndecryptablePackets = "meshtastic_undecryptable_packets_total"
+	MetricGatewayPackets       = "meshtastic_gateway_packets_total"
+	MetricDuplicatePackets     = "meshtastic_duplicate_packets_total"
+
+	MetricPositionLatitude      = "meshtastic_position_latitude_degrees"
+	MetricPositionLongitude     = "meshtastic_position_longitude_degrees"
+	MetricPositionAltitude      = "meshtastic_position_altitude_meters"
+	MetricPositionSatsInView    = "meshtastic_position_sats_in_view"
+	MetricPositionPrecisionBits = "meshtastic_position_precision_bits"
+
+	MetricGasResistance = "meshtastic_gas_resistance_megaohms"
+	MetricIAQ           = "meshtastic_iaq_index"
+	MetricPowerVoltage  = "meshtastic_power_voltage_volts"
+	MetricPowerCurrent  = "meshtastic_power_current_milliamps"
+
+	// Environment sensors
+	MetricDistance        = "meshtastic_distance_millimeters"
+	MetricLux             = "meshtastic_lux"
+	MetricWhiteLux        = "meshtastic_white_lux"
+	MetricIRLux           = "meshtastic_ir_lux"
+	MetricUVLux           = "meshtastic_uv_lux"
+	MetricWindDirection   = "meshtastic_wind_direction_degrees"
+	MetricWindSpeed       = "meshtastic_wind_speed_meters_per_second"
+	MetricWindGust        = "meshtastic_wind_gust_meters_per_second"
+	MetricWindLull        = "meshtastic_wind_lull_meters_per_second"
+	MetricWeight          = "meshtastic_weight_kilograms"
+	MetricRadiation       = "meshtastic_radiation_microroentgen_per_hour"
+	MetricRainfall1h      = "meshtastic_rainfall_1h_millimeters"
+	MetricRainfall24h     = "meshtastic_rainfall_24h_millimeters"
+	MetricSoilMoisture    = "meshtastic_soil_moisture_percent"
+	MetricSoilTemperature = "meshtastic_soil_temperature_celsius"
+
+	// Air quality sensors (PMSA003I, SCD4X)
+	MetricPM10Standard       = "meshtastic_pm1_0_standard_micrograms_per_cubic_meter"
+	MetricPM25Standard       = "meshtastic_pm2_5_standard_micrograms_per_cubic_meter"
+	MetricPM100Standard      = "meshtastic_pm10_standard_micrograms_per_cubic_meter"
+	MetricPM10Environmental  = "meshtastic_pm1_0_environmental_micrograms_per_cubic_meter"
+	MetricPM25Environmental  = "meshtastic_pm2_5_environmental_micrograms_per_cubic_meter"
+	MetricPM100Environmental = "meshtastic_pm10_environmental_micrograms_per_cubic_meter"
+	MetricParticles03um      = "meshtastic_particles_0_3um_per_deciliter"
+	MetricParticles05um      = "meshtastic_particles_0_5um_per_deciliter"
+	MetricParticles10um      = "meshtastic_particles_1_0um_per_deciliter"
+	MetricParticles25um      = "meshtastic_particles_2_5um_per_deciliter"
+	MetricParticles50um      = "meshtastic_particles_5_0um_per_deciliter"
+	MetricParticles100um     = "meshtastic_particles_10um_per_deciliter"
+	MetricCO2                = "meshtastic_co2_ppm"
+
+	// Производные метрики, вычисляются экспортером (derived_metrics)
+	MetricDewPoint         = "meshtastic_dew_point_celsius"
+	MetricHeatIndex        = "meshtastic_heat_index_celsius"
+	MetricAbsoluteHumidity = "meshtastic_absolute_humidity_grams_per_cubic_meter"
+	MetricSeaLevelPressure = "meshtastic_pressure_sea_level_hpa"
+
+	// LocalStats: счётчики с момента загрузки ноды, сбрасываются при перезагрузке
+	MetricLocalPacketsTx       = "meshtastic_local_packets_tx"
+	MetricLocalPacketsRx       = "meshtastic_local_packets_rx"
+	MetricLocalPacketsRxBad    = "meshtastic_local_packets_rx_bad"
+	MetricLocalPacketsRxDupe   = "meshtastic_local_packets_rx_dupe"
+	MetricLocalTxRelay         = "meshtastic_local_tx_relay"
+	MetricLocalTxRelayCanceled = "meshtastic_local_tx_relay_canceled"
+	MetricLocalOnlineNodes     = "meshtastic_local_online_nodes"
+	MetricLocalTotalNodes      = "meshtastic_local_total_nodes"
+	MetricLocalHeapTotalBytes  = "meshtastic_local_heap_total_bytes"
+	MetricLocalHeapFreeBytes   = "meshtastic_local_heap_free_bytes"
+
+	MetricPacketHops  = "meshtastic_packet_hops"
+	MetricNodeHops    = "meshtastic_node_hops_away"
+	MetricRelayPacket = "meshtastic_relay_packets_total"
+
+	MetricBatterySoC           = "meshtastic_battery_soc_percent"
+	MetricBatteryExternalPower = "meshtastic_battery_external_power"
+	MetricBatteryCharging      = "meshtastic_battery_charging"
+	MetricBatteryTimeToEmpty   = "meshtastic_battery_time_to_empty_seconds"
+
+	MetricPacketsExpected = "meshtastic_node_packets_expected_total"
+	MetricPacketsReceived = "meshtastic_node_packets_received_total"
+	MetricPacketGaps      = "meshtastic_node_packet_gaps_total"
+	MetricDeliveryRatio   = "meshtastic_node_delivery_ratio"
+
+	MetricIngestLatency    = "meshtastic_ingest_latency_seconds"
+	MetricGatewayClockSkew = "meshtastic_gateway_clock_skew_seconds"
+
+	MetricNodeReboots    = "meshtastic_node_reboots_total"
+	MetricNodeLastReboot = "meshtastic_node_last_reboot_timestamp"
+
+	MetricNodeFirmwareInfo     = "meshtastic_node_firmware_info"
+	MetricNodesByFirmware      = "meshtastic_nodes_by_firmware"
+	MetricNodeDefaultChannel   = "meshtastic_node_default_channel"
+	MetricNodeOnlineLocalNodes = "meshtastic_node_online_local_nodes"
+
+	MetricNeighborSNR               = "meshtastic_neighbor_snr_db"
+	MetricNeighborLastRx            = "meshtastic_neighbor_last_rx_timestamp"
+	MetricNeighborBroadcastInterval = "meshtastic_neighbor_broadcast_interval_seconds"
+
+	MetricTracerouteHopSNR = "meshtastic_traceroute_hop_snr_db"
+	MetricTracerouteRoute  = "meshtastic_traceroute_route_info"
+	MetricTracerouteHops   = "meshtastic_traceroute_hops"
+
+	MetricRoutingErrors = "meshtastic_routing_errors_total"
+	MetricRoutingAcks   = "meshtastic_routing_acks_total"
+
+	MetricDetectionEvents = "meshtastic_detection_events_total"
+	MetricDetectionState  = "meshtastic_detection_sensor_state"
+
+	MetricPaxcounterWiFi = "meshtastic_paxcounter_wifi"
+	MetricPaxcounterBLE  = "meshtastic_paxcounter_ble"
+
+	MetricRangeTestPackets  = "meshtastic_range_test_packets_total"
+	MetricRangeTestSequence = "meshtastic_range_test_sequence"
+	MetricRangeTestRSSI     = "meshtastic_range_test_rssi_dbm"
+	MetricRangeTestSNR      = "meshtastic_range_test_snr_db"
+	MetricRangeTestDistance = "meshtastic_range_test_distance_meters"
+
+	// PositionCoordinateDivider latitude_i/longitude_i хранятся в 1e-7 градуса
+	PositionCoordinateDivider = 1e7
+
+	// MaxHopLimit максимальный hop_limit в прошивке Meshtastic
+	MaxHopLimit = 7
+
+	// TracerouteSNRScale SNR в RouteDiscovery хранится умноженным на 4
+	TracerouteSNRScale = 4
+	// TracerouteSNRUnknown SNR хопа, который не записал своё значение (INT8_MIN)
+	TracerouteSNRUnknown = -128
+
+	// MaxRxTimeAge rx_time старше этого считается ошибкой часов шлюза, а не задержкой
+	MaxRxTimeAge = 24 * time.Hour
+	// ClockSkewWindow окно, в котором оценивается сдвиг часов шлюза
+	ClockSkewWindow = 10 * time.Minute
+
 	DefaultStateSaveInterval = 5 * time.Minute
 	StateFilePermissions     = 0600
 
 	DefaultTimeout       = 30 * time.Second
 	DefaultMetricsTTL    = 30 * time.Minute
+	DefaultDedupWindow   = time.Minute
 	DefaultKeepAlive     = 60 * time.Second
 	DefaultReadTimeout   = 15 * time.Second
 	DefaultWriteTimeout  = 15 * time.Second
@@ -33,10 +164,21 @@ const (
 	DefaultHeaderTimeout = 5 * time.Second
 
 	DefaultTopicPrefix = "msh/"
+	// DefaultProtobufPattern топики, на которые gateway публикуют ServiceEnvelope
+	DefaultProtobufPattern = "msh/+/+/e/#"
+	// DefaultMapReportPattern топики с MAP_REPORT_APP, которые ноды публикуют для карты
+	DefaultMapReportPattern = "msh/+/2/map/#"
+	// DefaultTopicTemplate шаблон для извлечения региона, канала и шлюза из топика
+	DefaultTopicTemplate = "msh/{region}/{version}/+/{channel}/{gateway}"
+	// DefaultChannelName/DefaultChannelKey публичный канал прошивки по умолчанию
+	DefaultChannelName = "LongFast"
+	DefaultChannelKey  = "AQ=="
 
 	DefaultHealthPath  = "/health"
 	DefaultMetricsPath = "/metrics"
 	DefaultAlertsPath  = "/alerts"
+	// DefaultTraceroutesPath последние трассировки в JSON
+	DefaultTraceroutesPath = "/api/traceroutes"
 
 	DefaultPrometheusHost = "localhost"
 	DefaultPrometheusPort = 8100
@@ -66,14 +208,22 @@ const (
 	MessageTypePosition     = "position"
 	MessageTypeWaypoint     = "waypoint"
 	MessageTypeNeighborInfo = "neighborinfo"
+	MessageTypeMapReport    = "mapreport"
+	MessageTypeTraceroute   = "traceroute"
+	MessageTypeRouting      = "routing"
+	MessageTypeDetection    = "detection"
+	MessageTypePaxcounter   = "paxcounter"
+	MessageTypeRangeTest    = "rangetest"
 	MessageTypeUnsupported  = "unsupported"
 
 	// Telemetry subtypes
 	TelemetryTypeDevice      = "device_metrics"
 	TelemetryTypeEnvironment = "environment_metrics"
 	TelemetryTypePower       = "power_metrics"
+	TelemetryTypeAirQuality  = "air_quality_metrics"
+	TelemetryTypeLocalStats  = "local_stats"
 )
 
 func GetDefaultMQTTTopics() []string {
-	return []string{"msh/+/+/json/+/+", "msh/2/json/+/+"}
+	return []string{"msh/+/+/json/+/+", "msh/2/json/+/+", "msh/+/+/e/+/+", "msh/+/2/map/#"}
 }
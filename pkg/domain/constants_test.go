@@ -12,6 +12,7 @@ func TestGetDefaultMQTTTopics(t *testing.T) {
 	assert.NotEmpty(t, topics)
 	assert.Contains(t, topics, "msh/+/+/json/+/+")
 	assert.Contains(t, topics, "msh/2/json/+/+")
+	assert.Contains(t, topics, DefaultMapReportPattern)
 
 	// Проверяем что возвращается копия, а не оригинальный слайс
 	originalLen := len(topics)
@@ -90,3 +91,16 @@ func TestDefaultValues(t *testing.T) {
 	assert.True(t, DefaultStateSaveInterval > 0)
 	assert.True(t, DefaultStateSaveInterval < DefaultMetricsTTL)
 }
+
+func TestSensorFields_Unique(t *testing.T) {
+	fields := make(map[string]bool)
+	metrics := make(map[string]bool)
+
+	for _, field := range SensorFields {
+		assert.False(t, fields[field.Field], "duplicate field %s", field.Field)
+		assert.False(t, metrics[field.Metric], "duplicate metric %s", field.Metric)
+		assert.Contains(t, []string{TelemetryTypeEnvironment, TelemetryTypeAirQuality, TelemetryTypeLocalStats}, field.Type)
+		fields[field.Field] = true
+		metrics[field.Metric] = true
+	}
+}
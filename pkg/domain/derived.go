@@ -0,0 +1,65 @@
+package domain
+
+import "math"
+
+// DerivedMetrics метрики, которые экспортер вычисляет из температуры, влажности,
+// давления и высоты ноды. Экспортируются так же, как SensorFields.
+var DerivedMetrics = []SensorField{
+	{Metric: MetricDewPoint, Help: "Dew point calculated from temperature and humidity", Type: TelemetryTypeEnvironment},
+	{Metric: MetricHeatIndex, Help: "Heat index calculated from temperature and humidity", Type: TelemetryTypeEnvironment},
+	{Metric: MetricAbsoluteHumidity, Help: "Absolute humidity calculated from temperature and humidity", Type: TelemetryTypeEnvironment},
+	{Metric: MetricSeaLevelPressure, Help: "Barometric pressure reduced to sea level using node altitude", Type: TelemetryTypeEnvironment},
+}
+
+// Коэффициенты формулы Магнуса (Alduchov & Eskridge), погрешность < 0.4 °C для -40..50 °C
+const (
+	magnusA = 17.625
+	magnusB = 243.04
+)
+
+// DewPoint точка росы в °C по формуле Магнуса, humidity в процентах (0..100].
+func DewPoint(temperature, humidity float64) float64 {
+	gamma := math.Log(humidity/100) + magnusA*temperature/(magnusB+temperature)
+	return magnusB * gamma / (magnusA - gamma)
+}
+
+// AbsoluteHumidity абсолютная влажность в г/м³.
+func AbsoluteHumidity(temperature, humidity float64) float64 {
+	// давление насыщенного пара в гПа, 2.1674 = 100 / R_v (461.5 Дж/(кг·К)) * 1000 г/кг
+	saturation := 6.112 * math.Exp(17.67*temperature/(temperature+243.5))
+	return saturation * humidity * 2.1674 / (273.15 + temperature)
+}
+
+// HeatIndex ощущаемая температура в °C по алгоритму NWS (регрессия Ротфуса с поправками).
+// Ниже ~27 °C совпадает с упрощённой формулой Стедмана и близок к самой температуре.
+func HeatIndex(temperature, humidity float64) float64 {
+	tf := temperature*9/5 + 32
+	hi := 0.5 * (tf + 61.0 + (tf-68.0)*1.2 + humidity*0.094)
+	if (hi+tf)/2 >= 80 {
+		hi = -42.379 + 2.04901523*tf + 10.14333127*humidity -
+			0.22475541*tf*humidity - 0.00683783*tf*tf - 0.05481717*humidity*humidity +
+			0.00122874*tf*tf*humidity + 0.00085282*tf*humidity*humidity -
+			0.00000199*tf*tf*humidity*humidity
+		hi += heatIndexAdjustment(tf, humidity)
+	}
+	return (hi - 32) * 5 / 9
+}
+
+func heatIndexAdjustment(tf, humidity float64) float64 {
+	if humidity < 13 && tf >= 80 && tf <= 112 {
+		return -((13 - humidity) / 4) * math.Sqrt((17-math.Abs(tf-95))/17)
+	}
+	if humidity > 85 && tf >= 80 && tf <= 87 {
+		return ((humidity - 85) / 10) * ((87 - tf) / 5)
+	}
+	return 0
+}
+
+// SeaLevelPressure приводит давление (гПа) к уровню моря по барометрической формуле.
+// Без температуры используется стандартная атмосфера.
+func SeaLevelPressure(pressure, altitude float64, temperature *float64) float64 {
+	if temperature == nil {
+		return pressure / math.Pow(1-altitude/44330, 5.255)
+	}
+	return pressure * math.Pow(1-0.0065*altitude/(*temperature+0.0065*altitude+273.15), -5.257)
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDerivedMetricFormulas(t *testing.T) {
+	t.Parallel()
+	temperature := 15.0
+	tests := []struct {
+		name     string
+		got      float64
+		expected float64
+	}{
+		{"dew point 20C 50%", DewPoint(20, 50), 9.26},
+		{"dew point at saturation", DewPoint(10, 100), 10},
+		{"absolute humidity 20C 50%", AbsoluteHumidity(20, 50), 8.64},
+		{"heat index below 27C", HeatIndex(20, 50), 19.36},
+		{"heat index 32C 70%", HeatIndex(32, 70), 40.41},
+		{"heat index dry adjustment", HeatIndex(40, 10), 36.71},
+		{"sea level standard atmosphere", SeaLevelPressure(1000, 500, nil), 1061.42},
+		{"sea level with temperature", SeaLevelPressure(1000, 500, &temperature), 1060.73},
+		{"sea level at zero altitude", SeaLevelPressure(1013.25, 0, &temperature), 1013.25},
+	}
+
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.expected) > 0.01 {
+			t.Errorf("%s = %.4f, expected %.2f", tt.name, tt.got, tt.expected)
+		}
+	}
+}
+
+func TestDerivedMetricsUnique(t *testing.T) {
+	t.Parallel()
+	seen := make(map[string]bool)
+	for _, field := range append(append([]SensorField{}, SensorFields...), DerivedMetrics...) {
+		if seen[field.Metric] {
+			t.Errorf("duplicate metric %s", field.Metric)
+		}
+		seen[field.Metric] = true
+	}
+}
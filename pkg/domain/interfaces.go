@@ -21,6 +21,43 @@ type MetricsCollector interface {
 	LoadState(filename string) error
 }
 
+// Необязательные возможности коллектора. Процессор проверяет их приведением типа,
+// поэтому внешние реализации MetricsCollector без этих методов продолжают работать.
+
+// PacketStatsCollector статистика приёма пакетов: шлюзы, дубли, хопы, ретрансляторы,
+// нерасшифрованные пакеты и оценка потерь.
+type PacketStatsCollector interface {
+	UpdateUndecryptableCounter(channel string)
+	CollectReception(r Reception)
+	UpdateDuplicateCounter(gatewayID string)
+	CollectPacketHops(nodeID string, hopsAway int)
+	UpdateRelayCounter(relayNode string)
+	CollectDelivery(stats DeliveryStats)
+}
+
+// ModuleCollector метрики модулей прошивки: map reports, трассировки, routing,
+// детектор, paxcounter и range test. Без него такие сообщения только считаются.
+type ModuleCollector interface {
+	CollectMapReport(report MapReport) error
+	CollectTraceroute(tr Traceroute) error
+	CollectRoutingResult(result RoutingResult) error
+	CollectDetection(event DetectionEvent) error
+	CollectPaxcount(pax Paxcount) error
+	CollectRangeTest(packet RangeTestPacket) error
+}
+
+// MappedValuesCollector метрики из hook.prometheus.mappings.
+type MappedValuesCollector interface {
+	CollectMappedValues(values []MappedValue)
+	// MappingRegistered коллектор зарегистрировал метрику маппинга, остальные процессор не вычисляет.
+	MappingRegistered(mapping MetricMapping) bool
+}
+
+// TracerouteStore последние трассировки, которые коллектор хранит в памяти для HTTP API.
+type TracerouteStore interface {
+	Traceroutes() []Traceroute
+}
+
 type AlertSender interface {
 	SendAlert(ctx context.Context, alert Alert) error
 }
@@ -73,6 +110,22 @@ type PrometheusConfig interface {
 	GetStateFile() string
 }
 
+// PrometheusProcessingConfig необязательные настройки разбора пакетов, проверяются
+// приведением типа PrometheusConfig. Без них protobuf, map reports и дополнительные
+// стадии обработки выключены, node_id в десятичном формате.
+type PrometheusProcessingConfig interface {
+	GetProtobufPattern() string
+	GetMapReportPattern() string
+	GetTopicTemplate() string
+	GetDedupWindow() time.Duration
+	GetNodeIDFormat() string
+	GetDerivedMetrics() bool
+	GetBatteryModel() BatteryModelConfig
+	GetPacketLoss() PacketLossConfig
+	GetMetricMappings() []MetricMapping
+	GetChannelKeys() map[string]string
+}
+
 type AlertManagerConfig interface {
 	GetListen() string
 	GetPath() string
@@ -0,0 +1,59 @@
+package domain
+
+// LoRaRegions значения Config.LoRaConfig.RegionCode из meshtastic/config.proto.
+var LoRaRegions = map[int]string{
+	0:  "UNSET",
+	1:  "US",
+	2:  "EU_433",
+	3:  "EU_868",
+	4:  "CN",
+	5:  "JP",
+	6:  "ANZ",
+	7:  "KR",
+	8:  "TW",
+	9:  "RU",
+	10: "IN",
+	11: "NZ_865",
+	12: "TH",
+	13: "LORA_24",
+	14: "UA_433",
+	15: "UA_868",
+	16: "MY_433",
+	17: "MY_919",
+	18: "SG_923",
+	19: "PH_433",
+	20: "PH_868",
+	21: "PH_915",
+	22: "ANZ_433",
+	23: "KZ_433",
+	24: "KZ_863",
+	25: "NP_865",
+	26: "BR_902",
+}
+
+// ModemPresets значения Config.LoRaConfig.ModemPreset из meshtastic/config.proto.
+var ModemPresets = map[int]string{
+	0: "LONG_FAST",
+	1: "LONG_SLOW",
+	2: "VERY_LONG_SLOW",
+	3: "MEDIUM_SLOW",
+	4: "MEDIUM_FAST",
+	5: "SHORT_SLOW",
+	6: "SHORT_FAST",
+	7: "LONG_MODERATE",
+	8: "SHORT_TURBO",
+}
+
+func GetRegionName(region int) string {
+	if name, exists := LoRaRegions[region]; exists {
+		return name
+	}
+	return "unknown"
+}
+
+func GetModemPresetName(preset int) string {
+	if name, exists := ModemPresets[preset]; exists {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// Типы метрик в hook.prometheus.mappings
+const (
+	MappingTypeGauge   = "gauge"
+	MappingTypeCounter = "counter"
+)
+
+// MetricMapping правило из hook.prometheus.mappings: поле payload сообщения MessageType -> метрика.
+// Пути — ключи payload через точку, элементы массивов по индексу: neighbors.0.snr.
+type MetricMapping struct {
+	MessageType string
+	Path        string // значение метрики, у counter без Path каждое сообщение добавляет 1
+	Metric      string
+	Type        string // gauge (по умолчанию) или counter
+	Unit        string
+	Help        string
+	Labels      map[string]string // имя лейбла -> путь к значению в payload, node_id добавляется всегда
+	Scale       float64           // множитель значения, 0 — без масштабирования
+}
+
+// MappedValue значение метрики маппинга для ноды.
+type MappedValue struct {
+	Metric string
+	NodeID string
+	Labels []string // значения в порядке MetricMapping.LabelNames
+	Value  float64
+}
+
+func (m MetricMapping) IsCounter() bool {
+	return m.Type == MappingTypeCounter
+}
+
+// MetricName имя метрики: к Metric добавляются _<unit> и _total для counter, если их ещё нет.
+func (m MetricMapping) MetricName() string {
+	name := m.Metric
+	if m.Unit != "" && !strings.HasSuffix(name, "_"+m.Unit) {
+		name += "_" + m.Unit
+	}
+	if m.IsCounter() && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+// Signature тип и лейблы метрики: маппинги с одним именем метрики должны совпадать по ним.
+func (m MetricMapping) Signature() string {
+	kind := MappingTypeGauge
+	if m.IsCounter() {
+		kind = MappingTypeCounter
+	}
+	return kind + "{" + strings.Join(m.LabelNames(), ",") + "}"
+}
+
+// LabelNames лейблы после node_id, отсортированы по имени.
+func (m MetricMapping) LabelNames() []string {
+	names := make([]string, 0, len(m.Labels))
+	for name := range m.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -26,10 +26,32 @@ func GetRoleName(role int) string {
 
 type MeshtasticMessage struct {
 	From    uint32                 `json:"from"`
+	To      uint32                 `json:"to,omitempty"`
+	ID      uint32                 `json:"id,omitempty"`
 	Type    string                 `json:"type"`
 	Payload map[string]interface{} `json:"payload"`
 	RSSI    *float64               `json:"rssi,omitempty"`
 	SNR     *float64               `json:"snr,omitempty"`
+	// GatewayID шлюз, отправивший пакет в MQTT (sender в JSON или последний сегмент топика)
+	GatewayID string `json:"sender,omitempty"`
+	// Region и Channel из шаблона топика (для protobuf канал берётся из ServiceEnvelope)
+	Region  string `json:"-"`
+	Channel string `json:"-"`
+
+	HopStart  *uint32 `json:"hop_start,omitempty"`
+	HopLimit  *uint32 `json:"hop_limit,omitempty"`
+	HopsAway  *uint32 `json:"hops_away,omitempty"`
+	RelayNode *uint32 `json:"relay_node,omitempty"` // только младший байт номера ноды-ретранслятора
+	// RequestID ID запроса, на который отвечает пакет (Data.request_id), в JSON прошивки его нет
+	RequestID uint32 `json:"-"`
+	// RawPayload байты Data.payload до разбора или JSON поля payload. Ссылаются на буфер
+	// сообщения: обработчик, который хранит их после возврата, должен их скопировать
+	RawPayload []byte `json:"-"`
+
+	// RxTime время приёма пакета по часам шлюза (timestamp в JSON, rx_time в MeshPacket), 0 — неизвестно
+	RxTime uint32 `json:"timestamp,omitempty"`
+	// ReceivedAt когда пакет услышан: RxTime, если ему можно верить, иначе время обработки
+	ReceivedAt time.Time `json:"-"`
 }
 
 type TelemetryData struct {
@@ -60,6 +82,45 @@ type TelemetryData struct {
 	Ch2Current *float64
 	Ch3Voltage *float64
 	Ch3Current *float64
+
+	// Sensors остальные сенсоры из SensorFields: имя метрики -> значение
+	Sensors map[string]float64
+
+	// Battery оценка модели батареи, nil если модель выключена или нет напряжения
+	Battery *BatteryEstimate
+}
+
+// TopicInfo поля, извлечённые из топика по шаблону.
+type TopicInfo struct {
+	Region  string
+	Version string
+	Channel string
+	Gateway string
+}
+
+// Reception приём пакета ноды шлюзом.
+type Reception struct {
+	NodeID      string
+	GatewayID   string
+	Region      string
+	Channel     string
+	MessageType string
+	RSSI        *float64
+	SNR         *float64
+	// RxTime время приёма по часам шлюза, нулевое если шлюз его не прислал
+	RxTime time.Time
+}
+
+// DeliveryStats оценка доставки пакетов ноды по счётчику в id пакетов или по интервалам
+// рассылки. Expected и Received — приращения для одного принятого пакета.
+type DeliveryStats struct {
+	NodeID   string
+	Stream   string // тип сообщения или подтип телеметрии
+	Expected int
+	Received int
+	Missed   int
+	// Ratio доля принятых пакетов за окно PacketLossConfig.Window, 0..1
+	Ratio float64
 }
 
 type NodeInfo struct {
@@ -67,6 +128,7 @@ type NodeInfo struct {
 	LongName  string
 	ShortName string
 	Hardware  string
+	HWModel   string // имя HardwareModel, например HELTEC_V3
 	Role      string
 	Timestamp time.Time
 }
@@ -88,6 +150,85 @@ type Position struct {
 	Timestamp     time.Time
 }
 
+// MapReport сводка, которую нода с включённым map reporting публикует в msh/<region>/2/map/.
+// Координаты в отчёте огрублены до Position.PrecisionBits.
+type MapReport struct {
+	NodeID            string
+	FirmwareVersion   string
+	Region            string
+	ModemPreset       string
+	HasDefaultChannel bool
+	OnlineLocalNodes  *int32
+	Position          Position
+	Timestamp         time.Time
+}
+
+// RoutingResult подтверждение или ошибка доставки из ROUTING_APP.
+// NodeID — нода, сообщившая результат: получатель для ACK, ретранслятор или отправитель для ошибки.
+type RoutingResult struct {
+	NodeID    string
+	Reason    string // имя Routing.Error, NONE для ACK
+	RequestID uint32 // ID пакета, к которому относится результат
+	Timestamp time.Time
+}
+
+// DetectionEvent сообщение DETECTION_SENSOR_APP: срабатывание датчика
+// или периодический отчёт о его состоянии.
+type DetectionEvent struct {
+	NodeID    string
+	Sensor    string // имя датчика из настроек модуля
+	Triggered bool   // срабатывание, а не отчёт о состоянии
+	State     float64
+	Timestamp time.Time
+}
+
+// Paxcount число устройств WiFi и BLE рядом с нодой (PAXCOUNTER_APP).
+type Paxcount struct {
+	NodeID    string
+	WiFi      uint32
+	BLE       uint32
+	Timestamp time.Time
+}
+
+// RangeTestPacket пакет RANGE_TEST_APP, услышанный шлюзом GatewayID.
+type RangeTestPacket struct {
+	NodeID    string
+	GatewayID string
+	Sequence  uint32
+	RSSI      *float64
+	SNR       *float64
+	Timestamp time.Time
+}
+
+// Направления хопа трассировки
+const (
+	TracerouteTowards = "towards"
+	TracerouteBack    = "back"
+)
+
+// Traceroute ответ на трассировку: путь от Origin до Destination и обратно.
+// Маршруты включают обе конечные ноды, обратный путь может быть неполным,
+// если шлюз услышал ответ до того, как он дошёл до Origin.
+type Traceroute struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	// OriginNum и DestinationNum десятичные номера нод, заполняются при node_id_format: both
+	OriginNum      uint32          `json:"origin_num,omitempty"`
+	DestinationNum uint32          `json:"destination_num,omitempty"`
+	Route          []string        `json:"route"`
+	RouteBack      []string        `json:"route_back,omitempty"`
+	Hops           []TracerouteHop `json:"hops"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// TracerouteHop хоп с известным SNR, измеренным нодой To при приёме от From.
+type TracerouteHop struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Direction string  `json:"direction"`
+	SNR       float64 `json:"snr"`
+}
+
 type Waypoint struct {
 	NodeID      string
 	WaypointID  int32
@@ -103,11 +244,17 @@ type Waypoint struct {
 
 type NeighborInfo struct {
 	NodeID                    string
+	NodeBroadcastIntervalSecs int32
+	Neighbors                 []Neighbor
+	Timestamp                 time.Time
+}
+
+// Neighbor ребро графа: нода, которую NodeID слышит напрямую.
+type Neighbor struct {
 	NeighborID                string
 	SNR                       float64
-	LastRxTime                int32
+	LastRxTime                int64
 	NodeBroadcastIntervalSecs int32
-	Timestamp                 time.Time
 }
 
 // Alert for LoRa network.
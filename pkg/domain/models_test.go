@@ -1,6 +1,9 @@
 package domain
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestGetRoleName(t *testing.T) {
 	t.Parallel()
@@ -22,3 +25,192 @@ func TestGetRoleName(t *testing.T) {
 		}
 	}
 }
+
+func TestGetRoutingErrorName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		reason   int
+		expected string
+	}{
+		{0, RoutingErrorNone},
+		{5, "MAX_RETRANSMIT"},
+		{9, "DUTY_CYCLE_LIMIT"},
+		{38, "RATE_LIMIT_EXCEEDED"},
+		{20, "unknown"},
+	}
+
+	for _, tt := range tests {
+		result := GetRoutingErrorName(tt.reason)
+		if result != tt.expected {
+			t.Errorf("GetRoutingErrorName(%d) = %s, expected %s", tt.reason, result, tt.expected)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		got      float64
+		expected float64
+	}{
+		{"same point", Distance(55.75, 37.62, 55.75, 37.62), 0},
+		{"one degree of latitude", Distance(0, 0, 1, 0), 111195.08},
+		{"one degree of longitude at 60N", Distance(60, 30, 60, 31), 55597.01},
+		{"antipodes", Distance(0, 0, 0, 180), 20015114.44},
+	}
+
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.expected) > 0.01 {
+			t.Errorf("%s = %.4f, expected %.2f", tt.name, tt.got, tt.expected)
+		}
+	}
+}
+
+func TestMetricMapping_MetricName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		mapping  MetricMapping
+		expected string
+	}{
+		{MetricMapping{Metric: "meshtastic_soil_ec"}, "meshtastic_soil_ec"},
+		{MetricMapping{Metric: "meshtastic_soil_ec", Unit: "microsiemens"}, "meshtastic_soil_ec_microsiemens"},
+		{MetricMapping{Metric: "meshtastic_depth_meters", Unit: "meters"}, "meshtastic_depth_meters"},
+		{MetricMapping{Metric: "meshtastic_rain", Unit: "millimeters", Type: MappingTypeCounter}, "meshtastic_rain_millimeters_total"},
+		{MetricMapping{Metric: "meshtastic_alarms_total", Type: MappingTypeCounter}, "meshtastic_alarms_total"},
+	}
+
+	for _, tt := range tests {
+		if result := tt.mapping.MetricName(); result != tt.expected {
+			t.Errorf("MetricName(%+v) = %s, expected %s", tt.mapping, result, tt.expected)
+		}
+	}
+}
+
+func TestGetHardwareModelName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		model    int
+		expected string
+	}{
+		{0, "UNSET"},
+		{4, "TBEAM"},
+		{9, "RAK4631"},
+		{43, "HELTEC_V3"},
+		{71, "TRACKER_T1000_E"},
+		{255, "PRIVATE_HW"},
+		{999, "unknown"},
+	}
+
+	for _, tt := range tests {
+		result := GetHardwareModelName(tt.model)
+		if result != tt.expected {
+			t.Errorf("GetHardwareModelName(%d) = %s, expected %s", tt.model, result, tt.expected)
+		}
+	}
+}
+
+func TestHardwareModelFromLabel(t *testing.T) {
+	t.Parallel()
+	if result := HardwareModelFromLabel("43"); result != "HELTEC_V3" {
+		t.Errorf("HardwareModelFromLabel(43) = %s, expected HELTEC_V3", result)
+	}
+	if result := HardwareModelFromLabel("unknown"); result != "unknown" {
+		t.Errorf("HardwareModelFromLabel(unknown) = %s, expected unknown", result)
+	}
+}
+
+func TestFormatNodeID(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{NodeIDFormatDecimal, "4187143508"},
+		{NodeIDFormatHex, "!f992bd54"},
+		{NodeIDFormatBoth, "!f992bd54"},
+		{"", "4187143508"},
+	}
+
+	for _, tt := range tests {
+		if result := FormatNodeID(0xf992bd54, tt.format); result != tt.expected {
+			t.Errorf("FormatNodeID(%q) = %s, expected %s", tt.format, result, tt.expected)
+		}
+	}
+	if result := FormatNodeID(0x1a, NodeIDFormatHex); result != "!0000001a" {
+		t.Errorf("FormatNodeID() = %s, expected zero padded !0000001a", result)
+	}
+}
+
+func TestParseNodeID(t *testing.T) {
+	t.Parallel()
+	for _, nodeID := range []string{"4187143508", "!f992bd54", "0xf992bd54", "0XF992BD54"} {
+		nodeNum, err := ParseNodeID(nodeID)
+		if err != nil || nodeNum != 0xf992bd54 {
+			t.Errorf("ParseNodeID(%s) = %d, %v", nodeID, nodeNum, err)
+		}
+	}
+	if _, err := ParseNodeID("node-1"); err == nil {
+		t.Error("ParseNodeID(node-1) expected error")
+	}
+}
+
+func TestConvertNodeID(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		nodeID   string
+		format   string
+		expected string
+	}{
+		{"4187143508", NodeIDFormatHex, "!f992bd54"},
+		{"!f992bd54", NodeIDFormatDecimal, "4187143508"},
+		{"!f992bd54", NodeIDFormatBoth, "!f992bd54"},
+		{"node-1", NodeIDFormatHex, "node-1"},
+	}
+
+	for _, tt := range tests {
+		if result := ConvertNodeID(tt.nodeID, tt.format); result != tt.expected {
+			t.Errorf("ConvertNodeID(%s, %s) = %s, expected %s", tt.nodeID, tt.format, result, tt.expected)
+		}
+	}
+}
+
+func TestBatteryCurve_StateOfCharge(t *testing.T) {
+	t.Parallel()
+	curve := BatteryCurves[BatteryChemistryLiIon]
+	tests := []struct {
+		voltage  float64
+		expected float64
+	}{
+		{2.9, 0},
+		{3.10, 0},
+		{3.80, 60},
+		{3.845, 65},
+		{4.19, 100},
+		{5.0, 100},
+	}
+
+	for _, tt := range tests {
+		if result := curve.StateOfCharge(tt.voltage); math.Abs(result-tt.expected) > 0.01 {
+			t.Errorf("StateOfCharge(%.3f) = %.2f, expected %.2f", tt.voltage, result, tt.expected)
+		}
+	}
+}
+
+func TestBatteryCurve_Valid(t *testing.T) {
+	t.Parallel()
+	for name, curve := range BatteryCurves {
+		if !curve.Valid() {
+			t.Errorf("builtin curve %s is invalid", name)
+		}
+	}
+	if (BatteryCurve{3.5}).Valid() {
+		t.Error("single point curve must be invalid")
+	}
+	if (BatteryCurve{4.2, 3.0}).Valid() {
+		t.Error("descending curve must be invalid")
+	}
+	if (BatteryCurve{3.0, 3.5, 3.5, 4.2}).Valid() {
+		t.Error("curve with equal voltages must be invalid")
+	}
+}
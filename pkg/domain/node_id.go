@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Форматы лейбла node_id
+const (
+	NodeIDFormatDecimal = "decimal" // 4187143508
+	NodeIDFormatHex     = "hex"     // !f992bd54, как в приложении Meshtastic
+	NodeIDFormatBoth    = "both"    // node_id в hex, десятичный номер в лейбле node_num у всех серий нод
+)
+
+func IsValidNodeIDFormat(format string) bool {
+	switch format {
+	case NodeIDFormatDecimal, NodeIDFormatHex, NodeIDFormatBoth:
+		return true
+	}
+	return false
+}
+
+// FormatNodeID форматирует номер ноды, неизвестный формат считается decimal.
+func FormatNodeID(nodeNum uint32, format string) string {
+	if format == NodeIDFormatHex || format == NodeIDFormatBoth {
+		return fmt.Sprintf("!%08x", nodeNum)
+	}
+	return strconv.FormatUint(uint64(nodeNum), 10)
+}
+
+// ParseNodeID разбирает номер ноды в десятичном виде, `!f992bd54` или `0xf992bd54`.
+func ParseNodeID(nodeID string) (uint32, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(nodeID, "!"):
+		nodeID, base = nodeID[1:], 16
+	case strings.HasPrefix(nodeID, "0x"), strings.HasPrefix(nodeID, "0X"):
+		nodeID, base = nodeID[2:], 16
+	}
+	val, err := strconv.ParseUint(nodeID, base, 32)
+	return uint32(val), err
+}
+
+// ConvertNodeID переводит node_id в заданный формат, нераспознанный ID возвращается как есть.
+func ConvertNodeID(nodeID, format string) string {
+	nodeNum, err := ParseNodeID(nodeID)
+	if err != nil {
+		return nodeID
+	}
+	return FormatNodeID(nodeNum, format)
+}
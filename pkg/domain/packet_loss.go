@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+const (
+	// DefaultPacketLossWindow окно, за которое считается доля принятых пакетов.
+	DefaultPacketLossWindow = 24 * time.Hour
+	// PacketIDCounterMask младшие биты id пакета — счётчик отправленных нодой пакетов,
+	// старшие биты прошивка заполняет случайно.
+	PacketIDCounterMask = 0x3FF
+	// MaxPacketIDGap больший шаг счётчика считаем перезагрузкой или случайными id старой прошивки.
+	MaxPacketIDGap = 32
+)
+
+// PacketLossConfig интервалы рассылки, по которым ожидаются пакеты от нод.
+type PacketLossConfig struct {
+	Enabled bool
+	// Intervals тип сообщения (nodeinfo, position) или подтип телеметрии
+	// (device_metrics, environment_metrics, ...) -> интервал рассылки
+	Intervals map[string]time.Duration
+	Window    time.Duration
+}
+
+// DefaultBroadcastIntervals интервалы по умолчанию в прошивке Meshtastic.
+var DefaultBroadcastIntervals = map[string]time.Duration{
+	TelemetryTypeDevice:      30 * time.Minute,
+	TelemetryTypeEnvironment: 30 * time.Minute,
+	MessageTypeNodeInfo:      3 * time.Hour,
+}
@@ -0,0 +1,32 @@
+package domain
+
+// RoutingErrorNone error_reason подтверждения (ACK)
+const RoutingErrorNone = "NONE"
+
+// RoutingErrors значения Routing.Error из meshtastic/mesh.proto.
+var RoutingErrors = map[int]string{
+	0:  RoutingErrorNone,
+	1:  "NO_ROUTE",
+	2:  "GOT_NAK",
+	3:  "TIMEOUT",
+	4:  "NO_INTERFACE",
+	5:  "MAX_RETRANSMIT",
+	6:  "NO_CHANNEL",
+	7:  "TOO_LARGE",
+	8:  "NO_RESPONSE",
+	9:  "DUTY_CYCLE_LIMIT",
+	32: "BAD_REQUEST",
+	33: "NOT_AUTHORIZED",
+	34: "PKI_FAILED",
+	35: "PKI_UNKNOWN_PUBKEY",
+	36: "ADMIN_BAD_SESSION_KEY",
+	37: "ADMIN_PUBLIC_KEY_UNAUTHORIZED",
+	38: "RATE_LIMIT_EXCEEDED",
+}
+
+func GetRoutingErrorName(reason int) string {
+	if name, exists := RoutingErrors[reason]; exists {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,54 @@
+package domain
+
+// SensorField поле телеметрии сенсора, которое экспортируется как gauge с лейблом node_id.
+type SensorField struct {
+	Field  string // ключ в payload (как в JSON прошивки)
+	Metric string
+	Help   string
+	Type   string // подтип телеметрии
+}
+
+// SensorFields дополнительные поля EnvironmentMetrics, AirQualityMetrics и LocalStats.
+// Единицы соответствуют тому, что шлёт прошивка, значения не пересчитываются.
+var SensorFields = []SensorField{
+	{Field: "distance", Metric: MetricDistance, Help: "Distance to water level or object", Type: TelemetryTypeEnvironment},
+	{Field: "lux", Metric: MetricLux, Help: "Ambient light", Type: TelemetryTypeEnvironment},
+	{Field: "white_lux", Metric: MetricWhiteLux, Help: "White light", Type: TelemetryTypeEnvironment},
+	{Field: "ir_lux", Metric: MetricIRLux, Help: "Infrared light", Type: TelemetryTypeEnvironment},
+	{Field: "uv_lux", Metric: MetricUVLux, Help: "Ultraviolet light", Type: TelemetryTypeEnvironment},
+	{Field: "wind_direction", Metric: MetricWindDirection, Help: "Wind direction", Type: TelemetryTypeEnvironment},
+	{Field: "wind_speed", Metric: MetricWindSpeed, Help: "Wind speed", Type: TelemetryTypeEnvironment},
+	{Field: "wind_gust", Metric: MetricWindGust, Help: "Wind gust", Type: TelemetryTypeEnvironment},
+	{Field: "wind_lull", Metric: MetricWindLull, Help: "Wind lull", Type: TelemetryTypeEnvironment},
+	{Field: "weight", Metric: MetricWeight, Help: "Weight", Type: TelemetryTypeEnvironment},
+	{Field: "radiation", Metric: MetricRadiation, Help: "Radiation dose rate", Type: TelemetryTypeEnvironment},
+	{Field: "rainfall_1h", Metric: MetricRainfall1h, Help: "Rainfall over the last hour", Type: TelemetryTypeEnvironment},
+	{Field: "rainfall_24h", Metric: MetricRainfall24h, Help: "Rainfall over the last 24 hours", Type: TelemetryTypeEnvironment},
+	{Field: "soil_moisture", Metric: MetricSoilMoisture, Help: "Soil moisture", Type: TelemetryTypeEnvironment},
+	{Field: "soil_temperature", Metric: MetricSoilTemperature, Help: "Soil temperature", Type: TelemetryTypeEnvironment},
+
+	{Field: "pm10", Metric: MetricPM10Standard, Help: "PM1.0 concentration, standard", Type: TelemetryTypeAirQuality},
+	{Field: "pm25", Metric: MetricPM25Standard, Help: "PM2.5 concentration, standard", Type: TelemetryTypeAirQuality},
+	{Field: "pm100", Metric: MetricPM100Standard, Help: "PM10 concentration, standard", Type: TelemetryTypeAirQuality},
+	{Field: "pm10_e", Metric: MetricPM10Environmental, Help: "PM1.0 concentration, environmental", Type: TelemetryTypeAirQuality},
+	{Field: "pm25_e", Metric: MetricPM25Environmental, Help: "PM2.5 concentration, environmental", Type: TelemetryTypeAirQuality},
+	{Field: "pm100_e", Metric: MetricPM100Environmental, Help: "PM10 concentration, environmental", Type: TelemetryTypeAirQuality},
+	{Field: "particles_03um", Metric: MetricParticles03um, Help: "Particles >0.3um in 0.1L of air", Type: TelemetryTypeAirQuality},
+	{Field: "particles_05um", Metric: MetricParticles05um, Help: "Particles >0.5um in 0.1L of air", Type: TelemetryTypeAirQuality},
+	{Field: "particles_10um", Metric: MetricParticles10um, Help: "Particles >1.0um in 0.1L of air", Type: TelemetryTypeAirQuality},
+	{Field: "particles_25um", Metric: MetricParticles25um, Help: "Particles >2.5um in 0.1L of air", Type: TelemetryTypeAirQuality},
+	{Field: "particles_50um", Metric: MetricParticles50um, Help: "Particles >5.0um in 0.1L of air", Type: TelemetryTypeAirQuality},
+	{Field: "particles_100um", Metric: MetricParticles100um, Help: "Particles >10um in 0.1L of air", Type: TelemetryTypeAirQuality},
+	{Field: "co2", Metric: MetricCO2, Help: "CO2 concentration", Type: TelemetryTypeAirQuality},
+
+	{Field: "num_packets_tx", Metric: MetricLocalPacketsTx, Help: "Packets sent since boot", Type: TelemetryTypeLocalStats},
+	{Field: "num_packets_rx", Metric: MetricLocalPacketsRx, Help: "Packets received since boot", Type: TelemetryTypeLocalStats},
+	{Field: "num_packets_rx_bad", Metric: MetricLocalPacketsRxBad, Help: "Malformed or undecryptable packets received since boot", Type: TelemetryTypeLocalStats},
+	{Field: "num_rx_dupe", Metric: MetricLocalPacketsRxDupe, Help: "Duplicate packets received since boot", Type: TelemetryTypeLocalStats},
+	{Field: "num_tx_relay", Metric: MetricLocalTxRelay, Help: "Packets relayed since boot", Type: TelemetryTypeLocalStats},
+	{Field: "num_tx_relay_canceled", Metric: MetricLocalTxRelayCanceled, Help: "Relays canceled because another node relayed first", Type: TelemetryTypeLocalStats},
+	{Field: "num_online_nodes", Metric: MetricLocalOnlineNodes, Help: "Nodes heard in the last 2 hours", Type: TelemetryTypeLocalStats},
+	{Field: "num_total_nodes", Metric: MetricLocalTotalNodes, Help: "Nodes in the node database", Type: TelemetryTypeLocalStats},
+	{Field: "heap_total_bytes", Metric: MetricLocalHeapTotalBytes, Help: "Total heap size", Type: TelemetryTypeLocalStats},
+	{Field: "heap_free_bytes", Metric: MetricLocalHeapFreeBytes, Help: "Free heap", Type: TelemetryTypeLocalStats},
+}
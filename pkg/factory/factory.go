@@ -12,6 +12,7 @@ import (
 type Factory struct {
 	config    domain.Config
 	collector domain.MetricsCollector
+	handlers  *application.HandlerRegistry
 }
 
 func NewFactory(config domain.Config) *Factory {
@@ -23,6 +24,11 @@ func NewDefaultFactory() *Factory {
 	return &Factory{config: nil}
 }
 
+// SetMessageHandlers обработчики встраивающего приложения для процессоров, созданных после вызова.
+func (f *Factory) SetMessageHandlers(handlers *application.HandlerRegistry) {
+	f.handlers = handlers
+}
+
 func (f *Factory) CreateMetricsCollector() domain.MetricsCollector {
 	return f.CreateMetricsCollectorWithMode("hook")
 }
@@ -31,8 +37,15 @@ func (f *Factory) CreateMetricsCollectorWithMode(mode string) domain.MetricsColl
 	if f.collector == nil {
 		if f.config != nil {
 			prometheusConfig := f.config.GetPrometheusConfig()
-			ttl := prometheusConfig.GetMetricsTTL()
-			f.collector = infrastructure.NewPrometheusCollectorWithConfig(mode, ttl)
+			opts := infrastructure.CollectorOptions{
+				Mode:       mode,
+				MetricsTTL: prometheusConfig.GetMetricsTTL(),
+			}
+			if processing, ok := prometheusConfig.(domain.PrometheusProcessingConfig); ok {
+				opts.NodeIDFormat = processing.GetNodeIDFormat()
+				opts.Mappings = processing.GetMetricMappings()
+			}
+			f.collector = infrastructure.NewPrometheusCollectorWithOptions(opts)
 
 			if stateFile := prometheusConfig.GetStateFile(); stateFile != "" {
 				if err := f.collector.LoadState(stateFile); err != nil {
@@ -78,14 +91,30 @@ func (f *Factory) CreateAlertSenderWithMQTT(mqttServer interface{}) domain.Alert
 func (f *Factory) CreateMessageProcessor() domain.MessageProcessor {
 	collector := f.CreateMetricsCollector()
 	alerter := f.CreateAlertSender()
-	logAllMessages := false
-	topicPattern := ""
+	opts := application.ProcessorOptions{Handlers: f.handlers}
 	if f.config != nil {
 		prometheusConfig := f.config.GetPrometheusConfig()
-		logAllMessages = prometheusConfig.GetLogAllMessages()
-		topicPattern = prometheusConfig.GetTopicPattern()
+		opts.LogAllMessages = prometheusConfig.GetLogAllMessages()
+		opts.TopicPattern = prometheusConfig.GetTopicPattern()
+		opts.MetricsTTL = prometheusConfig.GetMetricsTTL()
+		if processing, ok := prometheusConfig.(domain.PrometheusProcessingConfig); ok {
+			applyProcessingConfig(&opts, processing)
+		}
 	}
-	return application.NewMeshtasticProcessor(collector, alerter, logAllMessages, topicPattern)
+	return application.NewMeshtasticProcessorWithOptions(collector, alerter, opts)
+}
+
+func applyProcessingConfig(opts *application.ProcessorOptions, processing domain.PrometheusProcessingConfig) {
+	opts.ProtobufPattern = processing.GetProtobufPattern()
+	opts.MapReportPattern = processing.GetMapReportPattern()
+	opts.TopicTemplate = processing.GetTopicTemplate()
+	opts.DedupWindow = processing.GetDedupWindow()
+	opts.NodeIDFormat = processing.GetNodeIDFormat()
+	opts.DerivedMetrics = processing.GetDerivedMetrics()
+	opts.Battery = processing.GetBatteryModel()
+	opts.PacketLoss = processing.GetPacketLoss()
+	opts.Mappings = processing.GetMetricMappings()
+	opts.ChannelKeys = processing.GetChannelKeys()
 }
 
 func (f *Factory) CreateMQTTClient(processor domain.MessageProcessor) *infrastructure.MQTTClient {
@@ -19,11 +19,14 @@ import (
 )
 
 type MeshtasticHookConfig struct {
-	ServerAddr   string
-	EnableHealth bool
-	TopicPrefix  string
-	MetricsTTL   time.Duration
-	AlertPath    string
+	ServerAddr      string
+	EnableHealth    bool
+	TopicPrefix     string
+	ProtobufPattern string
+	// MapReportPattern топики msh/+/2/map/, тоже protobuf ServiceEnvelope
+	MapReportPattern string
+	MetricsTTL       time.Duration
+	AlertPath        string
 }
 
 type MeshtasticHook struct {
@@ -86,6 +89,10 @@ func NewMeshtasticHookSimple(f *factory.Factory) *MeshtasticHook {
 	if f != nil {
 		if promConfig := f.GetPrometheusConfig(); promConfig != nil {
 			config.ServerAddr = promConfig.GetListen()
+			if processing, ok := promConfig.(domain.PrometheusProcessingConfig); ok {
+				config.ProtobufPattern = processing.GetProtobufPattern()
+				config.MapReportPattern = processing.GetMapReportPattern()
+			}
 		}
 		if alertConfig := f.GetAlertManagerConfig(); alertConfig != nil {
 			config.AlertPath = alertConfig.GetPath()
@@ -188,7 +195,7 @@ func (h *MeshtasticHook) OnPublish(_ *mqtt.Client, pk packets.Packet) (packets.P
 	//	Msg("received MQTT message")
 
 	// Проверяем соответствие топика паттерну
-	if !h.matchesTopicPattern(pk.TopicName) {
+	if !h.matchesTopicPattern(pk.TopicName) && !h.matchesProtobufPattern(pk.TopicName) {
 		//h.logger.Debug().
 		//	Str("topic", pk.TopicName).
 		//	Str("expected_prefix", h.config.TopicPrefix).
@@ -286,8 +293,19 @@ func (h *MeshtasticHook) checkFileWritable(filename string) error {
 }
 
 func (h *MeshtasticHook) matchesTopicPattern(topic string) bool {
+	return matchesPattern(topic, h.config.TopicPrefix)
+}
+
+func (h *MeshtasticHook) matchesProtobufPattern(topic string) bool {
+	if h.config.MapReportPattern != "" && matchesPattern(topic, h.config.MapReportPattern) {
+		return true
+	}
+	return h.config.ProtobufPattern != "" && matchesPattern(topic, h.config.ProtobufPattern)
+}
+
+func matchesPattern(topic, pattern string) bool {
 	parts := strings.Split(topic, "/")
-	patternParts := strings.Split(strings.TrimSuffix(h.config.TopicPrefix, "/"), "/")
+	patternParts := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
 
 	for i, patternPart := range patternParts {
 		if patternPart == "#" {
@@ -0,0 +1,28 @@
+package infrastructure
+
+import (
+	"math"
+	"time"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+// skewWindow скользящий максимум за два соседних окна domain.ClockSkewWindow,
+// чтобы оценка обновлялась после исправления часов шлюза.
+type skewWindow struct {
+	start    time.Time
+	current  float64
+	previous float64
+}
+
+func (w *skewWindow) observe(offset float64, now time.Time) float64 {
+	switch {
+	case w.start.IsZero() || now.Sub(w.start) >= 2*domain.ClockSkewWindow:
+		w.start, w.current, w.previous = now, offset, math.Inf(-1)
+	case now.Sub(w.start) >= domain.ClockSkewWindow:
+		w.start, w.previous, w.current = now, w.current, offset
+	default:
+		w.current = math.Max(w.current, offset)
+	}
+	return math.Max(w.current, w.previous)
+}
@@ -0,0 +1,57 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+func TestSkewWindow(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1700000000, 0)
+	window := &skewWindow{}
+
+	assert.InDelta(t, -5, window.observe(-5, start), 0.001)
+	// задержанный пакет не уменьшает оценку
+	assert.InDelta(t, -2, window.observe(-2, start.Add(time.Minute)), 0.001)
+	assert.InDelta(t, -2, window.observe(-30, start.Add(2*time.Minute)), 0.001)
+
+	// в следующем окне максимум прошлого окна ещё учитывается
+	assert.InDelta(t, -2, window.observe(-20, start.Add(domain.ClockSkewWindow+time.Minute)), 0.001)
+	// через два окна старые значения забываются
+	assert.InDelta(t, -20, window.observe(-25, start.Add(2*domain.ClockSkewWindow+2*time.Minute)), 0.001)
+	assert.InDelta(t, 120, window.observe(120, start.Add(5*domain.ClockSkewWindow)), 0.001)
+}
+
+func TestPrometheusCollector_CollectReceptionRxTime(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	now := time.Now()
+	collector.CollectReception(domain.Reception{NodeID: "123", GatewayID: "!aaaa0001", RxTime: now.Add(-3 * time.Second)})
+	collector.CollectReception(domain.Reception{NodeID: "123", GatewayID: "!bbbb0002", RxTime: now.Add(90 * time.Second)})
+	collector.CollectReception(domain.Reception{NodeID: "123", GatewayID: "!cccc0003"})
+
+	assert.InDelta(t, -3, testutil.ToFloat64(collector.gatewaySkew.WithLabelValues("!aaaa0001")), 1)
+	assert.InDelta(t, 90, testutil.ToFloat64(collector.gatewaySkew.WithLabelValues("!bbbb0002")), 1)
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.gatewaySkew))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.ingestLatency))
+}
+
+func TestPrometheusCollector_UpdateNodeLastSeenMonotonic(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	collector.UpdateNodeLastSeen("123", time.Unix(1700000100, 0))
+	collector.UpdateNodeLastSeen("123", time.Unix(1700000050, 0))
+	assert.InDelta(t, 1700000100, testutil.ToFloat64(collector.nodeLastSeen.WithLabelValues("123")), 0.001)
+
+	collector.UpdateNodeLastSeen("123", time.Unix(1700000200, 0))
+	assert.InDelta(t, 1700000200, testutil.ToFloat64(collector.nodeLastSeen.WithLabelValues("123")), 0.001)
+}
@@ -0,0 +1,130 @@
+package infrastructure
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"meshtastic-exporter/pkg/domain"
+	"meshtastic-exporter/pkg/logger"
+)
+
+// mappedLabelSeparator разделяет значения лейблов маппинга в ключе TTL. Управляющих
+// символов в значениях нет: строки из payload проходят SanitizeString.
+const mappedLabelSeparator = "\x00"
+
+// mappedMetric метрика из hook.prometheus.mappings с лейблом node_id и лейблами маппинга.
+type mappedMetric struct {
+	gauge     *prometheus.GaugeVec
+	counter   *prometheus.CounterVec
+	labels    []string // лейблы после node_id
+	signature string
+}
+
+func newMappedMetric(mapping domain.MetricMapping) *mappedMetric {
+	help := mapping.Help
+	if help == "" {
+		help = "Mapped from " + mapping.MessageType + " " + mapping.Path
+	}
+	labels := mapping.LabelNames()
+	names := append([]string{"node_id"}, labels...)
+
+	if mapping.IsCounter() {
+		return &mappedMetric{
+			counter:   prometheus.NewCounterVec(prometheus.CounterOpts{Name: mapping.MetricName(), Help: help}, names),
+			labels:    labels,
+			signature: mapping.Signature(),
+		}
+	}
+	return &mappedMetric{
+		gauge:     prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: mapping.MetricName(), Help: help}, names),
+		labels:    labels,
+		signature: mapping.Signature(),
+	}
+}
+
+func (m *mappedMetric) collector() prometheus.Collector {
+	if m.counter != nil {
+		return m.counter
+	}
+	return m.gauge
+}
+
+// persisted gauge без дополнительных лейблов живёт как встроенные метрики ноды:
+// TTL и state_file через nodeGauges. Серии с лейблами маппинга не сохраняются.
+func (m *mappedMetric) persisted() bool {
+	return m.gauge != nil && len(m.labels) == 0
+}
+
+// setupMappedMetrics регистрирует метрики маппингов. Маппинги одной метрики
+// из разных типов сообщений делят одну серию, если совпадают тип и лейблы. Маппинг
+// с другими типом или лейблами и конфликт с уже зарегистрированной метрикой
+// пропускаются с предупреждением.
+func (c *PrometheusCollector) setupMappedMetrics(mappings []domain.MetricMapping) {
+	c.mapped = make(map[string]*mappedMetric)
+	log := logger.ComponentLogger(metricsCollectorComponent)
+
+	for _, mapping := range mappings {
+		name := mapping.MetricName()
+		if registered, exists := c.mapped[name]; exists {
+			if registered.signature != mapping.Signature() {
+				log.Warn().Str("metric", name).Str("declared", registered.signature).Str("mapping", mapping.Signature()).Msg("mapping skipped")
+			}
+			continue
+		}
+		metric := newMappedMetric(mapping)
+		if err := c.registry.Register(metric.collector()); err != nil {
+			log.Warn().Err(err).Str("metric", name).Msg("mapping skipped")
+			continue
+		}
+		c.mapped[name] = metric
+		if metric.persisted() {
+			c.nodeGauges[name] = metric.gauge
+		}
+		if metric.counter != nil {
+			c.expiringVecs[name] = nodeVec{vec: metric.counter.MetricVec, label: "node_id"}
+		}
+	}
+}
+
+func (c *PrometheusCollector) MappingRegistered(mapping domain.MetricMapping) bool {
+	metric, exists := c.mapped[mapping.MetricName()]
+	return exists && metric.signature == mapping.Signature()
+}
+
+func (c *PrometheusCollector) CollectMappedValues(values []domain.MappedValue) {
+	for _, value := range values {
+		metric, exists := c.mapped[value.Metric]
+		if !exists || len(value.Labels) != len(metric.labels) {
+			continue
+		}
+		labelValues := append([]string{value.NodeID}, value.Labels...)
+		if metric.counter != nil {
+			if value.Value > 0 {
+				c.addCounter(metric.counter, value.Metric, value.Value, labelValues...)
+			}
+			continue
+		}
+		metric.gauge.WithLabelValues(labelValues...).Set(value.Value)
+		c.updateMetricTimestamp(value.NodeID, mappedKey(value.Metric, value.Labels))
+	}
+}
+
+// mappedKey ключ TTL: имя метрики для gauge без лейблов, как у nodeGauges,
+// иначе seriesKey с значениями лейблов через mappedLabelSeparator.
+func mappedKey(metricName string, labels []string) string {
+	if len(labels) == 0 {
+		return metricName
+	}
+	return seriesKey(metricName, strings.Join(labels, mappedLabelSeparator))
+}
+
+// deleteMappedSeries удаляет устаревшую серию маппинга с лейблами.
+func (c *PrometheusCollector) deleteMappedSeries(nodeID, metricName, labelValues string) {
+	metric, exists := c.mapped[metricName]
+	if !exists || metric.gauge == nil {
+		return
+	}
+	values := strings.Split(labelValues, mappedLabelSeparator)
+	metric.gauge.DeleteLabelValues(append([]string{nodeID}, values...)...)
+}
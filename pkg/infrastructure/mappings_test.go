@@ -0,0 +1,96 @@
+package infrastructure
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+var testMappings = []domain.MetricMapping{
+	{MessageType: "telemetry", Path: "soil_conductivity", Metric: "meshtastic_soil_conductivity", Type: domain.MappingTypeGauge},
+	{MessageType: "telemetry", Path: "probe.depth", Metric: "meshtastic_probe_depth", Type: domain.MappingTypeGauge,
+		Labels: map[string]string{"probe": "probe.id", "model": "probe.model"}},
+	{MessageType: "text", Metric: "meshtastic_texts", Type: domain.MappingTypeCounter},
+	// встроенная метрика не перезаписывается
+	{MessageType: "telemetry", Path: "voltage", Metric: domain.MetricVoltage, Type: domain.MappingTypeGauge},
+}
+
+func TestPrometheusCollector_CollectMappedValues(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithOptions(CollectorOptions{Mode: "test", Mappings: testMappings})
+	defer collector.Shutdown()
+
+	collector.CollectMappedValues([]domain.MappedValue{
+		{Metric: "meshtastic_soil_conductivity", NodeID: "123", Labels: []string{}, Value: 850},
+		{Metric: "meshtastic_probe_depth", NodeID: "123", Labels: []string{"THC|S", "2"}, Value: 30},
+		{Metric: "meshtastic_texts_total", NodeID: "123", Labels: []string{}, Value: 1},
+		{Metric: "meshtastic_texts_total", NodeID: "123", Labels: []string{}, Value: 1},
+		{Metric: "meshtastic_probe_depth", NodeID: "123", Labels: []string{"wrong arity"}, Value: 1},
+		{Metric: domain.MetricVoltage, NodeID: "123", Labels: []string{}, Value: 1},
+	})
+
+	soil := collector.mapped["meshtastic_soil_conductivity"].gauge
+	depth := collector.mapped["meshtastic_probe_depth"].gauge
+	assert.InDelta(t, 850, testutil.ToFloat64(soil.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 30, testutil.ToFloat64(depth.WithLabelValues("123", "THC|S", "2")), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.mapped["meshtastic_texts_total"].counter.WithLabelValues("123")), 0.001)
+	assert.NotContains(t, collector.mapped, domain.MetricVoltage)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.voltage))
+	assert.Equal(t, 1, testutil.CollectAndCount(depth))
+
+	// устаревшие серии удаляются по TTL вместе с лейблами маппинга
+	collector.mu.Lock()
+	for metricName := range collector.metricTimestamps["123"] {
+		collector.deleteMetric("123", metricName)
+	}
+	collector.mu.Unlock()
+
+	assert.Equal(t, 0, testutil.CollectAndCount(soil))
+	assert.Equal(t, 0, testutil.CollectAndCount(depth))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.mapped["meshtastic_texts_total"].counter))
+}
+
+func TestPrometheusCollector_MappingRegistered(t *testing.T) {
+	t.Parallel()
+	mappings := append(append([]domain.MetricMapping{}, testMappings...),
+		// та же метрика из другого типа сообщений делит серию
+		domain.MetricMapping{MessageType: "position", Path: "soil", Metric: "meshtastic_soil_conductivity", Type: domain.MappingTypeGauge},
+		// те же имя и число лейблов, но другие лейблы: значения попали бы не в те лейблы
+		domain.MetricMapping{MessageType: "position", Path: "probe.depth", Metric: "meshtastic_probe_depth", Type: domain.MappingTypeGauge,
+			Labels: map[string]string{"sensor": "probe.id", "slot": "probe.model"}},
+	)
+	collector := NewPrometheusCollectorWithOptions(CollectorOptions{Mode: "test", Mappings: mappings})
+	defer collector.Shutdown()
+
+	assert.True(t, collector.MappingRegistered(testMappings[1]))
+	assert.True(t, collector.MappingRegistered(testMappings[2]))
+	assert.False(t, collector.MappingRegistered(testMappings[3]), "builtin metric conflict")
+	assert.True(t, collector.MappingRegistered(mappings[4]))
+	assert.False(t, collector.MappingRegistered(mappings[5]), "first declaration wins")
+}
+
+func TestPrometheusCollector_StatePersistenceMappedMetrics(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithOptions(CollectorOptions{Mode: "test", Mappings: testMappings})
+	defer collector.Shutdown()
+	collector.CollectMappedValues([]domain.MappedValue{
+		{Metric: "meshtastic_soil_conductivity", NodeID: "123", Labels: []string{}, Value: 850},
+		{Metric: "meshtastic_probe_depth", NodeID: "123", Labels: []string{"THC-S", "2"}, Value: 30},
+	})
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, collector.SaveState(stateFile))
+
+	restored := NewPrometheusCollectorWithOptions(CollectorOptions{Mode: "test", MetricsTTL: time.Minute, Mappings: testMappings})
+	defer restored.Shutdown()
+	require.NoError(t, restored.LoadState(stateFile))
+
+	assert.InDelta(t, 850, testutil.ToFloat64(restored.mapped["meshtastic_soil_conductivity"].gauge.WithLabelValues("123")), 0.001)
+	assert.Equal(t, 0, testutil.CollectAndCount(restored.mapped["meshtastic_probe_depth"].gauge))
+}
@@ -3,7 +3,10 @@ package infrastructure
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,12 +23,33 @@ const (
 	metricsCollectorComponent = "metrics-collector"
 	minCleanupInterval        = 500 * time.Millisecond
 	maxCleanupInterval        = 30 * time.Second
+	// seriesKeySeparator разделяет имя метрики и значение второго лейбла (neighbor_id, channel)
+	// в ключах TTL и файла состояния
+	seriesKeySeparator = "|"
 )
 
+// seriesGauge gauge с лейблами node_id и label (neighbor_id, channel, gateway_id).
+type seriesGauge struct {
+	vec     *prometheus.GaugeVec
+	label   string
+	persist bool // сохранять в файл состояния
+}
+
 type PrometheusCollector struct {
 	registry *prometheus.Registry
+	exposed  *prometheus.Registry // реестр GetRegistry, в формате both добавляет номера нод
 
 	messageCounter *prometheus.CounterVec
+	undecryptable  *prometheus.CounterVec
+	gatewayPackets *prometheus.CounterVec
+	duplicates     *prometheus.CounterVec
+	ingestLatency  *prometheus.HistogramVec
+	gatewaySkew    *prometheus.GaugeVec
+	relayPackets   *prometheus.CounterVec
+	routingErrors  *prometheus.CounterVec
+	routingAcks    *prometheus.CounterVec
+	packetHops     prometheus.Histogram
+	nodeHops       *prometheus.GaugeVec
 	batteryLevel   *prometheus.GaugeVec
 	voltage        *prometheus.GaugeVec
 	temperature    *prometheus.GaugeVec
@@ -34,16 +58,109 @@ type PrometheusCollector struct {
 	channelUtil    *prometheus.GaugeVec
 	airUtilTx      *prometheus.GaugeVec
 	uptime         *prometheus.GaugeVec
-	rssi           *prometheus.GaugeVec
-	snr            *prometheus.GaugeVec
-	nodeLastSeen   *prometheus.GaugeVec
-	nodeHardware   *prometheus.GaugeVec
-	serviceInfo    *prometheus.GaugeVec
+	nodeReboots    *prometheus.CounterVec
+	batterySoC     *prometheus.GaugeVec
+	batteryPower   *prometheus.GaugeVec
+	batteryCharge  *prometheus.GaugeVec
+	batteryTTE     *prometheus.GaugeVec
+
+	packetsExpected *prometheus.CounterVec
+	packetsReceived *prometheus.CounterVec
+	packetGaps      *prometheus.CounterVec
+	deliveryRatio   *prometheus.GaugeVec
+	nodeLastReboot  *prometheus.GaugeVec
+	rssi            *prometheus.GaugeVec
+	snr             *prometheus.GaugeVec
+	nodeLastSeen    *prometheus.GaugeVec
+	nodeHardware    *prometheus.GaugeVec
+	nodesByHW       *prometheus.GaugeVec
+	serviceInfo     *prometheus.GaugeVec
+
+	firmwareInfo     *prometheus.GaugeVec
+	nodesByFirmware  *prometheus.GaugeVec
+	defaultChannel   *prometheus.GaugeVec
+	onlineLocalNodes *prometheus.GaugeVec
+
+	gasResistance *prometheus.GaugeVec
+	iaq           *prometheus.GaugeVec
+	powerVoltage  *prometheus.GaugeVec
+	powerCurrent  *prometheus.GaugeVec
+	sensorGauges  map[string]*prometheus.GaugeVec // metricName -> gauge из domain.SensorFields и domain.DerivedMetrics
+
+	posLatitude      *prometheus.GaugeVec
+	posLongitude     *prometheus.GaugeVec
+	posAltitude      *prometheus.GaugeVec
+	posSatsInView    *prometheus.GaugeVec
+	posPrecisionBits *prometheus.GaugeVec
+
+	neighborSNR               *prometheus.GaugeVec
+	neighborLastRx            *prometheus.GaugeVec
+	neighborBroadcastInterval *prometheus.GaugeVec
+
+	hopSNR     *prometheus.GaugeVec
+	routeInfo  *prometheus.GaugeVec
+	routeHops  *prometheus.GaugeVec
+	traceroute map[string]domain.Traceroute // origin|destination -> последний ответ на трассировку
+
+	detectionEvents  *prometheus.CounterVec
+	detectionState   *prometheus.GaugeVec
+	paxWiFi          *prometheus.GaugeVec
+	paxBLE           *prometheus.GaugeVec
+	rangeTestPackets *prometheus.CounterVec
+	rangeSequence    *prometheus.GaugeVec
+	rangeRSSI        *prometheus.GaugeVec
+	rangeSNR         *prometheus.GaugeVec
+	rangeDistance    *prometheus.GaugeVec
+	positions        map[string]coordinates // nodeID -> последние координаты для расстояния range test
+
+	nodeModels   map[string]string       // nodeID -> hw_model для meshtastic_nodes_by_hardware
+	nodeFirmware map[string]firmwareInfo // nodeID -> лейблы meshtastic_node_firmware_info
+	nodeIDFormat string
+	lastUptime   map[string]uptimeSample // nodeID -> последний uptime для обнаружения перезагрузок
+	lastSeen     map[string]time.Time
+	clockSkew    map[string]*skewWindow // gatewayID -> оценка сдвига часов
+
+	nodeGauges   map[string]*prometheus.GaugeVec // metricName -> gauge с единственным лейблом node_id
+	seriesGauges map[string]seriesGauge          // metricName -> gauge с лейблом node_id и ещё одним лейблом
+	mapped       map[string]*mappedMetric        // metricName -> метрика из hook.prometheus.mappings
+	expiringVecs map[string]nodeVec              // metricName -> счётчики и метрики шлюзов, TTL по первому лейблу
+	nodeVecs     map[string]nodeVec              // metricName -> любая метрика с сериями по нодам
 
 	metricTimestamps map[string]map[string]time.Time // nodeID -> metricName -> timestamp
-	metricsTTL       time.Duration
-	cleanupCancel    context.CancelFunc
-	mu               sync.RWMutex
+	// gatewayTimestamps TTL метрик шлюзов и ретрансляторов отдельно от нод: в формате hex
+	// gateway_id шлюза совпадает с node_id его ноды, а забывать ноду — не значит забывать шлюз
+	gatewayTimestamps map[string]map[string]time.Time // gateway_id или relay_node -> metricName -> timestamp
+	metricsTTL        time.Duration
+	cleanupCancel     context.CancelFunc
+	mu                sync.RWMutex
+}
+
+// nodeVec метрика с сериями по нодам или шлюзам, label — лейбл с node_id или gateway_id.
+type nodeVec struct {
+	vec   *prometheus.MetricVec
+	label string
+}
+
+// firmwareInfo лейблы meshtastic_node_firmware_info из последнего map report ноды.
+type firmwareInfo struct {
+	version     string
+	region      string
+	modemPreset string
+}
+
+type coordinates struct {
+	latitude  float64
+	longitude float64
+}
+
+// CollectorOptions настройки коллектора, нулевые значения заменяются значениями по умолчанию.
+type CollectorOptions struct {
+	Mode       string
+	MetricsTTL time.Duration
+	// NodeIDFormat формат лейбла node_id, node_id из файла состояния переводятся в него при загрузке.
+	NodeIDFormat string
+	// Mappings метрики из полей payload, объявленные в конфигурации.
+	Mappings []domain.MetricMapping
 }
 
 func NewPrometheusCollector() *PrometheusCollector {
@@ -59,20 +176,41 @@ func NewPrometheusCollectorWithMode(mode string) *PrometheusCollector {
 }
 
 func NewPrometheusCollectorWithConfig(mode string, ttl time.Duration) *PrometheusCollector {
+	return NewPrometheusCollectorWithOptions(CollectorOptions{Mode: mode, MetricsTTL: ttl})
+}
+
+func NewPrometheusCollectorWithOptions(opts CollectorOptions) *PrometheusCollector {
 	registry := prometheus.NewRegistry()
 
+	ttl := opts.MetricsTTL
 	if ttl <= 0 {
 		ttl = domain.DefaultMetricsTTL
 	}
+	nodeIDFormat := opts.NodeIDFormat
+	if nodeIDFormat == "" {
+		nodeIDFormat = domain.NodeIDFormatDecimal
+	}
 
 	collector := &PrometheusCollector{
-		registry:         registry,
-		metricTimestamps: make(map[string]map[string]time.Time),
-		metricsTTL:       ttl,
+		registry:          registry,
+		metricTimestamps:  make(map[string]map[string]time.Time),
+		gatewayTimestamps: make(map[string]map[string]time.Time),
+		nodeModels:        make(map[string]string),
+		nodeFirmware:      make(map[string]firmwareInfo),
+		traceroute:        make(map[string]domain.Traceroute),
+		positions:         make(map[string]coordinates),
+		lastUptime:        make(map[string]uptimeSample),
+		lastSeen:          make(map[string]time.Time),
+		clockSkew:         make(map[string]*skewWindow),
+		nodeIDFormat:      nodeIDFormat,
+		metricsTTL:        ttl,
 	}
 
 	collector.setupMetrics()
-	collector.setupServiceInfo(mode)
+	collector.setupMappedMetrics(opts.Mappings)
+	collector.setupNodeVecs()
+	collector.setupServiceInfo(opts.Mode)
+	collector.setupExposedRegistry()
 	if ttl > 0 {
 		go collector.startMetricsTTLCleanup()
 	}
@@ -84,6 +222,10 @@ func (c *PrometheusCollector) setupMetrics() {
 		prometheus.CounterOpts{Name: domain.MetricMessagesTotal, Help: "Total messages by type"},
 		[]string{"type", "from_node"})
 
+	c.undecryptable = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricUndecryptablePackets, Help: "Encrypted packets without a matching channel key or with invalid decrypted content"},
+		[]string{"channel"})
+
 	c.batteryLevel = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{Name: domain.MetricBatteryLevel, Help: "Battery level"},
 		[]string{"node_id"})
@@ -117,12 +259,16 @@ func (c *PrometheusCollector) setupMetrics() {
 		[]string{"node_id"})
 
 	c.rssi = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{Name: domain.MetricRSSI, Help: "RSSI signal strength"},
-		[]string{"node_id"})
+		prometheus.GaugeOpts{Name: domain.MetricRSSI, Help: "RSSI signal strength at the gateway"},
+		[]string{"node_id", "gateway_id"})
 
 	c.snr = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{Name: domain.MetricSNR, Help: "Signal-to-noise ratio"},
-		[]string{"node_id"})
+		prometheus.GaugeOpts{Name: domain.MetricSNR, Help: "Signal-to-noise ratio at the gateway"},
+		[]string{"node_id", "gateway_id"})
+
+	c.gatewayPackets = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricGatewayPackets, Help: "Packets uplinked by gateway"},
+		[]string{"gateway_id", "type", "region", "channel"})
 
 	c.nodeLastSeen = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{Name: domain.MetricNodeLastSeen, Help: "Last seen timestamp"},
@@ -130,15 +276,392 @@ func (c *PrometheusCollector) setupMetrics() {
 
 	c.nodeHardware = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{Name: domain.MetricNodeInfo, Help: "Node information"},
-		[]string{"node_id", "longname", "shortname", "hardware", "role"})
+		[]string{"node_id", "longname", "shortname", "hardware", "hw_model", "role"})
+
+	c.nodesByHW = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodesByHW, Help: "Known nodes by hardware model"},
+		[]string{"hw_model"})
+
+	c.duplicates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricDuplicatePackets, Help: "Packets already received via another gateway"},
+		[]string{"gateway_id"})
 
 	c.registry.MustRegister(
 		c.messageCounter, c.batteryLevel, c.voltage, c.temperature,
 		c.humidity, c.pressure, c.channelUtil, c.airUtilTx,
-		c.uptime, c.rssi, c.snr, c.nodeLastSeen, c.nodeHardware,
+		c.uptime, c.rssi, c.snr, c.nodeLastSeen, c.nodeHardware, c.nodesByHW,
+		c.undecryptable, c.gatewayPackets, c.duplicates,
+	)
+
+	c.setupRoutingMetrics()
+	c.setupTimingMetrics()
+	c.setupSensorMetrics()
+	c.setupPositionMetrics()
+	c.setupNeighborMetrics()
+	c.setupMapReportMetrics()
+	c.setupTracerouteMetrics()
+	c.setupModuleMetrics()
+	c.setupRebootMetrics()
+	c.setupBatteryMetrics()
+	c.setupDeliveryMetrics()
+	c.setupNodeGauges()
+	c.setupSeriesGauges()
+	c.setupExpiringVecs()
+}
+
+func (c *PrometheusCollector) setupRoutingMetrics() {
+	c.packetHops = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    domain.MetricPacketHops,
+			Help:    "Hops travelled by received packets",
+			Buckets: prometheus.LinearBuckets(0, 1, domain.MaxHopLimit+1),
+		})
+
+	c.nodeHops = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodeHops, Help: "Hops away of the last packet from the node"},
+		[]string{"node_id"})
+
+	c.relayPackets = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricRelayPacket, Help: "Packets by last relay node (lowest byte of node number)"},
+		[]string{"relay_node"})
+
+	c.routingErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricRoutingErrors, Help: "Routing errors (NAK) reported by the node"},
+		[]string{"node_id", "reason"})
+
+	c.routingAcks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricRoutingAcks, Help: "Delivery acknowledgements sent by the node"},
+		[]string{"node_id"})
+
+	c.registry.MustRegister(c.packetHops, c.nodeHops, c.relayPackets, c.routingErrors, c.routingAcks)
+}
+
+func (c *PrometheusCollector) setupTimingMetrics() {
+	c.ingestLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    domain.MetricIngestLatency,
+			Help:    "Delay between gateway rx_time and processing",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600},
+		},
+		[]string{"gateway_id"})
+
+	c.gatewaySkew = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricGatewayClockSkew, Help: "Estimated gateway clock offset, positive when gateway clock is ahead"},
+		[]string{"gateway_id"})
+
+	c.registry.MustRegister(c.ingestLatency, c.gatewaySkew)
+}
+
+func (c *PrometheusCollector) setupMapReportMetrics() {
+	c.firmwareInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodeFirmwareInfo, Help: "Firmware and LoRa settings from the last map report, always 1"},
+		[]string{"node_id", "firmware_version", "region", "modem_preset"})
+
+	c.nodesByFirmware = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodesByFirmware, Help: "Nodes with map reporting by firmware version"},
+		[]string{"firmware_version"})
+
+	c.defaultChannel = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodeDefaultChannel, Help: "Node uses the default primary channel (1) or not (0)"},
+		[]string{"node_id"})
+
+	c.onlineLocalNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodeOnlineLocalNodes, Help: "Online nodes in the local mesh reported by the node"},
+		[]string{"node_id"})
+
+	c.registry.MustRegister(c.firmwareInfo, c.nodesByFirmware, c.defaultChannel, c.onlineLocalNodes)
+}
+
+func (c *PrometheusCollector) setupRebootMetrics() {
+	c.nodeReboots = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricNodeReboots, Help: "Node reboots detected by uptime decrease"},
+		[]string{"node_id"})
+
+	c.nodeLastReboot = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNodeLastReboot, Help: "Estimated boot time of the last detected reboot"},
+		[]string{"node_id"})
+
+	c.registry.MustRegister(c.nodeReboots, c.nodeLastReboot)
+}
+
+func (c *PrometheusCollector) setupBatteryMetrics() {
+	c.batterySoC = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricBatterySoC, Help: "Battery state of charge estimated from voltage curve"},
+		[]string{"node_id"})
+
+	c.batteryPower = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricBatteryExternalPower, Help: "Node is on external power (1) or battery (0)"},
+		[]string{"node_id"})
+
+	c.batteryCharge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricBatteryCharging, Help: "Battery is charging (1) or not (0)"},
+		[]string{"node_id"})
+
+	c.batteryTTE = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricBatteryTimeToEmpty, Help: "Forecast time until battery is empty at current discharge rate"},
+		[]string{"node_id"})
+
+	c.registry.MustRegister(c.batterySoC, c.batteryPower, c.batteryCharge, c.batteryTTE)
+}
+
+func (c *PrometheusCollector) setupDeliveryMetrics() {
+	c.packetsExpected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricPacketsExpected, Help: "Packets expected from the node by packet id counter or broadcast intervals"},
+		[]string{"node_id"})
+
+	c.packetsReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricPacketsReceived, Help: "Packets received from the node and counted in the loss estimate"},
+		[]string{"node_id"})
+
+	c.packetGaps = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricPacketGaps, Help: "Gaps with one or more missed packets"},
+		[]string{"node_id", "stream"})
+
+	c.deliveryRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricDeliveryRatio, Help: "Estimated share of expected packets received within the packet loss window"},
+		[]string{"node_id"})
+
+	c.registry.MustRegister(c.packetsExpected, c.packetsReceived, c.packetGaps, c.deliveryRatio)
+}
+
+func (c *PrometheusCollector) setupSensorMetrics() {
+	c.gasResistance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricGasResistance, Help: "Gas resistance"},
+		[]string{"node_id"})
+
+	c.iaq = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricIAQ, Help: "Indoor air quality index"},
+		[]string{"node_id"})
+
+	c.powerVoltage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPowerVoltage, Help: "Power sensor channel voltage"},
+		[]string{"node_id", "channel"})
+
+	c.powerCurrent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPowerCurrent, Help: "Power sensor channel current"},
+		[]string{"node_id", "channel"})
+
+	c.registry.MustRegister(c.gasResistance, c.iaq, c.powerVoltage, c.powerCurrent)
+
+	fields := append(append([]domain.SensorField{}, domain.SensorFields...), domain.DerivedMetrics...)
+	c.sensorGauges = make(map[string]*prometheus.GaugeVec, len(fields))
+	for _, field := range fields {
+		gauge := prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: field.Metric, Help: field.Help},
+			[]string{"node_id"})
+		c.registry.MustRegister(gauge)
+		c.sensorGauges[field.Metric] = gauge
+	}
+
+}
+
+func (c *PrometheusCollector) setupNeighborMetrics() {
+	c.neighborSNR = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNeighborSNR, Help: "SNR of a directly heard neighbor"},
+		[]string{"node_id", "neighbor_id"})
+
+	c.neighborLastRx = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNeighborLastRx, Help: "Last time the neighbor was heard"},
+		[]string{"node_id", "neighbor_id"})
+
+	c.neighborBroadcastInterval = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricNeighborBroadcastInterval, Help: "Neighbor info broadcast interval"},
+		[]string{"node_id", "neighbor_id"})
+
+	c.registry.MustRegister(c.neighborSNR, c.neighborLastRx, c.neighborBroadcastInterval)
+}
+
+func (c *PrometheusCollector) setupTracerouteMetrics() {
+	c.hopSNR = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricTracerouteHopSNR, Help: "SNR measured by node to when receiving from node from in a traceroute"},
+		[]string{"from", "to"})
+
+	c.routeInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricTracerouteRoute, Help: "Last traceroute path between origin and destination, always 1"},
+		[]string{"origin", "destination", "route", "route_back"})
+
+	c.routeHops = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricTracerouteHops, Help: "Hops in the last traceroute path"},
+		[]string{"origin", "destination", "direction"})
+
+	c.registry.MustRegister(c.hopSNR, c.routeInfo, c.routeHops)
+}
+
+// setupModuleMetrics метрики модулей прошивки: Detection Sensor, Paxcounter и Range Test.
+func (c *PrometheusCollector) setupModuleMetrics() {
+	c.detectionEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricDetectionEvents, Help: "Detection sensor triggers"},
+		[]string{"node_id", "sensor"})
+
+	c.detectionState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricDetectionState, Help: "Last reported detection sensor state"},
+		[]string{"node_id", "sensor"})
+
+	c.paxWiFi = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPaxcounterWiFi, Help: "WiFi devices seen by the paxcounter"},
+		[]string{"node_id"})
+
+	c.paxBLE = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPaxcounterBLE, Help: "BLE devices seen by the paxcounter"},
+		[]string{"node_id"})
+
+	c.rangeTestPackets = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: domain.MetricRangeTestPackets, Help: "Range test packets received by the gateway"},
+		[]string{"node_id", "gateway_id"})
+
+	c.rangeSequence = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricRangeTestSequence, Help: "Sequence number of the last range test packet"},
+		[]string{"node_id", "gateway_id"})
+
+	c.rangeRSSI = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricRangeTestRSSI, Help: "RSSI of the last range test packet"},
+		[]string{"node_id", "gateway_id"})
+
+	c.rangeSNR = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricRangeTestSNR, Help: "SNR of the last range test packet"},
+		[]string{"node_id", "gateway_id"})
+
+	c.rangeDistance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricRangeTestDistance, Help: "Distance between sender and gateway positions"},
+		[]string{"node_id", "gateway_id"})
+
+	c.registry.MustRegister(
+		c.detectionEvents, c.detectionState, c.paxWiFi, c.paxBLE,
+		c.rangeTestPackets, c.rangeSequence, c.rangeRSSI, c.rangeSNR, c.rangeDistance,
 	)
 }
 
+func (c *PrometheusCollector) setupPositionMetrics() {
+	c.posLatitude = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPositionLatitude, Help: "Latitude"},
+		[]string{"node_id"})
+
+	c.posLongitude = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPositionLongitude, Help: "Longitude"},
+		[]string{"node_id"})
+
+	c.posAltitude = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPositionAltitude, Help: "Altitude above MSL"},
+		[]string{"node_id"})
+
+	c.posSatsInView = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPositionSatsInView, Help: "GPS satellites in view"},
+		[]string{"node_id"})
+
+	c.posPrecisionBits = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: domain.MetricPositionPrecisionBits, Help: "Position precision bits"},
+		[]string{"node_id"})
+
+	c.registry.MustRegister(c.posLatitude, c.posLongitude, c.posAltitude, c.posSatsInView, c.posPrecisionBits)
+}
+
+// setupNodeGauges gauges с лейблом node_id, общие для восстановления состояния и TTL очистки.
+func (c *PrometheusCollector) setupNodeGauges() {
+	c.nodeGauges = map[string]*prometheus.GaugeVec{
+		domain.MetricBatteryLevel:          c.batteryLevel,
+		domain.MetricVoltage:               c.voltage,
+		domain.MetricTemperature:           c.temperature,
+		domain.MetricHumidity:              c.humidity,
+		domain.MetricPressure:              c.pressure,
+		domain.MetricChannelUtil:           c.channelUtil,
+		domain.MetricAirUtilTx:             c.airUtilTx,
+		domain.MetricUptime:                c.uptime,
+		domain.MetricNodeLastReboot:        c.nodeLastReboot,
+		domain.MetricBatterySoC:            c.batterySoC,
+		domain.MetricBatteryExternalPower:  c.batteryPower,
+		domain.MetricBatteryCharging:       c.batteryCharge,
+		domain.MetricBatteryTimeToEmpty:    c.batteryTTE,
+		domain.MetricDeliveryRatio:         c.deliveryRatio,
+		domain.MetricNodeDefaultChannel:    c.defaultChannel,
+		domain.MetricNodeOnlineLocalNodes:  c.onlineLocalNodes,
+		domain.MetricPaxcounterWiFi:        c.paxWiFi,
+		domain.MetricPaxcounterBLE:         c.paxBLE,
+		domain.MetricNodeLastSeen:          c.nodeLastSeen,
+		domain.MetricNodeHops:              c.nodeHops,
+		domain.MetricGasResistance:         c.gasResistance,
+		domain.MetricIAQ:                   c.iaq,
+		domain.MetricPositionLatitude:      c.posLatitude,
+		domain.MetricPositionLongitude:     c.posLongitude,
+		domain.MetricPositionAltitude:      c.posAltitude,
+		domain.MetricPositionSatsInView:    c.posSatsInView,
+		domain.MetricPositionPrecisionBits: c.posPrecisionBits,
+	}
+	for metricName, gauge := range c.sensorGauges {
+		c.nodeGauges[metricName] = gauge
+	}
+}
+
+// setupSeriesGauges gauges с двумя лейблами, у каждой серии свой TTL.
+// Рёбра графа соседей не сохраняем: это снимок топологии, он быстро устаревает.
+// Результаты range test тоже: тест — короткая сессия.
+func (c *PrometheusCollector) setupSeriesGauges() {
+	c.seriesGauges = map[string]seriesGauge{
+		domain.MetricRSSI:                      {vec: c.rssi, label: "gateway_id", persist: true},
+		domain.MetricSNR:                       {vec: c.snr, label: "gateway_id", persist: true},
+		domain.MetricPowerVoltage:              {vec: c.powerVoltage, label: "channel", persist: true},
+		domain.MetricPowerCurrent:              {vec: c.powerCurrent, label: "channel", persist: true},
+		domain.MetricNeighborSNR:               {vec: c.neighborSNR, label: "neighbor_id"},
+		domain.MetricNeighborLastRx:            {vec: c.neighborLastRx, label: "neighbor_id"},
+		domain.MetricNeighborBroadcastInterval: {vec: c.neighborBroadcastInterval, label: "neighbor_id"},
+		domain.MetricTracerouteHopSNR:          {vec: c.hopSNR, label: "to"},
+		domain.MetricDetectionState:            {vec: c.detectionState, label: "sensor", persist: true},
+		domain.MetricRangeTestSequence:         {vec: c.rangeSequence, label: "gateway_id"},
+		domain.MetricRangeTestRSSI:             {vec: c.rangeRSSI, label: "gateway_id"},
+		domain.MetricRangeTestSNR:              {vec: c.rangeSNR, label: "gateway_id"},
+		domain.MetricRangeTestDistance:         {vec: c.rangeDistance, label: "gateway_id"},
+	}
+}
+
+// setupExpiringVecs счётчики по нодам и метрики шлюзов. Серии удаляются целиком
+// по первому лейблу, если за TTL у них не было обновлений.
+func (c *PrometheusCollector) setupExpiringVecs() {
+	c.expiringVecs = map[string]nodeVec{
+		domain.MetricNodeReboots:      {vec: c.nodeReboots.MetricVec, label: "node_id"},
+		domain.MetricRoutingErrors:    {vec: c.routingErrors.MetricVec, label: "node_id"},
+		domain.MetricRoutingAcks:      {vec: c.routingAcks.MetricVec, label: "node_id"},
+		domain.MetricPacketsExpected:  {vec: c.packetsExpected.MetricVec, label: "node_id"},
+		domain.MetricPacketsReceived:  {vec: c.packetsReceived.MetricVec, label: "node_id"},
+		domain.MetricPacketGaps:       {vec: c.packetGaps.MetricVec, label: "node_id"},
+		domain.MetricDetectionEvents:  {vec: c.detectionEvents.MetricVec, label: "node_id"},
+		domain.MetricRangeTestPackets: {vec: c.rangeTestPackets.MetricVec, label: "node_id"},
+		domain.MetricRelayPacket:      {vec: c.relayPackets.MetricVec, label: "relay_node"},
+		domain.MetricGatewayPackets:   {vec: c.gatewayPackets.MetricVec, label: "gateway_id"},
+		domain.MetricDuplicatePackets: {vec: c.duplicates.MetricVec, label: "gateway_id"},
+		domain.MetricIngestLatency:    {vec: c.ingestLatency.MetricVec, label: "gateway_id"},
+		domain.MetricGatewayClockSkew: {vec: c.gatewaySkew.MetricVec, label: "gateway_id"},
+	}
+}
+
+// setupNodeVecs все метрики с сериями по нодам, включая счётчики без TTL:
+// по ним удаляются серии ноды целиком.
+func (c *PrometheusCollector) setupNodeVecs() {
+	c.nodeVecs = map[string]nodeVec{
+		domain.MetricMessagesTotal:    {vec: c.messageCounter.MetricVec, label: "from_node"},
+		domain.MetricNodeInfo:         {vec: c.nodeHardware.MetricVec, label: "node_id"},
+		domain.MetricNodeFirmwareInfo: {vec: c.firmwareInfo.MetricVec, label: "node_id"},
+	}
+	for metricName, metric := range c.expiringVecs {
+		if metric.label == "node_id" {
+			c.nodeVecs[metricName] = metric
+		}
+	}
+	for metricName, gauge := range c.nodeGauges {
+		c.nodeVecs[metricName] = nodeVec{vec: gauge.MetricVec, label: "node_id"}
+	}
+	for metricName, series := range c.seriesGauges {
+		c.nodeVecs[metricName] = nodeVec{vec: series.vec.MetricVec, label: "node_id"}
+	}
+	// хоп трассировки принадлежит ноде from
+	c.nodeVecs[domain.MetricTracerouteHopSNR] = nodeVec{vec: c.hopSNR.MetricVec, label: "from"}
+	for metricName, metric := range c.mapped {
+		if metric.gauge != nil {
+			c.nodeVecs[metricName] = nodeVec{vec: metric.gauge.MetricVec, label: "node_id"}
+		} else {
+			c.nodeVecs[metricName] = nodeVec{vec: metric.counter.MetricVec, label: "node_id"}
+		}
+	}
+}
+
 func (c *PrometheusCollector) setupServiceInfo(mode string) {
 	c.serviceInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{Name: domain.MetricExporterInfo, Help: "Service information"},
@@ -147,6 +670,16 @@ func (c *PrometheusCollector) setupServiceInfo(mode string) {
 	c.updateServiceInfo(mode)
 }
 
+// setupExposedRegistry в формате both метрики отдаются через nodeNumCollector,
+// файл состояния пишется из исходного реестра без номеров.
+func (c *PrometheusCollector) setupExposedRegistry() {
+	c.exposed = c.registry
+	if c.nodeIDFormat == domain.NodeIDFormatBoth {
+		c.exposed = prometheus.NewRegistry()
+		c.exposed.MustRegister(nodeNumCollector{gatherer: c.registry})
+	}
+}
+
 func (c *PrometheusCollector) updateServiceInfo(mode string) {
 	version, gitCommit, buildDate := getVersionInfo()
 	c.serviceInfo.WithLabelValues(version, mode, gitCommit, buildDate).Set(1)
@@ -157,7 +690,7 @@ func getVersionInfo() (string, string, string) {
 }
 
 func (c *PrometheusCollector) CollectTelemetry(data domain.TelemetryData) error {
-	c.UpdateNodeLastSeen(data.NodeID, time.Now())
+	c.UpdateNodeLastSeen(data.NodeID, observedAt(data.Timestamp))
 	c.UpdateMessageCounter(data.NodeID, domain.MessageTypeTelemetry)
 	c.setTelemetryMetrics(data)
 	return nil
@@ -166,6 +699,8 @@ func (c *PrometheusCollector) CollectTelemetry(data domain.TelemetryData) error
 func (c *PrometheusCollector) setTelemetryMetrics(data domain.TelemetryData) {
 	c.setBasicMetrics(data)
 	c.setEnvironmentalMetrics(data)
+	c.setPowerMetrics(data)
+	c.setBatteryEstimate(data)
 	c.setNetworkMetrics(data)
 }
 
@@ -178,9 +713,37 @@ func (c *PrometheusCollector) setBasicMetrics(data domain.TelemetryData) {
 	}
 	if data.UptimeSeconds != nil {
 		c.uptime.WithLabelValues(data.NodeID).Set(*data.UptimeSeconds)
+		c.detectReboot(data.NodeID, *data.UptimeSeconds, observedAt(data.Timestamp))
 	}
 }
 
+// uptimeSample uptime ноды и время пакета, в котором он пришёл.
+type uptimeSample struct {
+	uptime float64
+	at     time.Time
+}
+
+// detectReboot считает перезагрузкой падение uptime больше, чем прошло между пакетами:
+// поздняя доставка или копия от другого шлюза так не выглядит. Пакеты старше
+// последнего учтённого пропускаются.
+func (c *PrometheusCollector) detectReboot(nodeID string, uptime float64, at time.Time) {
+	c.mu.Lock()
+	previous, known := c.lastUptime[nodeID]
+	if known && at.Before(previous.at) {
+		c.mu.Unlock()
+		return
+	}
+	c.lastUptime[nodeID] = uptimeSample{uptime: uptime, at: at}
+	c.mu.Unlock()
+
+	if !known || previous.uptime-uptime <= at.Sub(previous.at).Seconds() {
+		return
+	}
+	c.addCounter(c.nodeReboots, domain.MetricNodeReboots, 1, nodeID)
+	bootTime := at.Add(-time.Duration(uptime) * time.Second)
+	c.setNodeGauge(c.nodeLastReboot, nodeID, domain.MetricNodeLastReboot, float64(bootTime.Unix()))
+}
+
 func (c *PrometheusCollector) setEnvironmentalMetrics(data domain.TelemetryData) {
 	if data.Temperature != nil {
 		c.temperature.WithLabelValues(data.NodeID).Set(*data.Temperature)
@@ -194,6 +757,50 @@ func (c *PrometheusCollector) setEnvironmentalMetrics(data domain.TelemetryData)
 		c.pressure.WithLabelValues(data.NodeID).Set(*data.BarometricPressure)
 		c.updateMetricTimestamp(data.NodeID, domain.MetricPressure)
 	}
+	if data.GasResistance != nil {
+		c.setNodeGauge(c.gasResistance, data.NodeID, domain.MetricGasResistance, *data.GasResistance)
+	}
+	if data.IAQ != nil {
+		c.setNodeGauge(c.iaq, data.NodeID, domain.MetricIAQ, *data.IAQ)
+	}
+	for metricName, value := range data.Sensors {
+		if gauge, exists := c.sensorGauges[metricName]; exists {
+			c.setNodeGauge(gauge, data.NodeID, metricName, value)
+		}
+	}
+}
+
+// setPowerMetrics каналы INA219/INA3221, номер канала уходит в лейбл channel.
+func (c *PrometheusCollector) setPowerMetrics(data domain.TelemetryData) {
+	channels := []struct {
+		channel string
+		voltage *float64
+		current *float64
+	}{
+		{"1", data.Ch1Voltage, data.Ch1Current},
+		{"2", data.Ch2Voltage, data.Ch2Current},
+		{"3", data.Ch3Voltage, data.Ch3Current},
+	}
+
+	for _, ch := range channels {
+		if ch.voltage != nil {
+			c.setSeriesGauge(c.powerVoltage, data.NodeID, ch.channel, domain.MetricPowerVoltage, *ch.voltage)
+		}
+		if ch.current != nil {
+			c.setSeriesGauge(c.powerCurrent, data.NodeID, ch.channel, domain.MetricPowerCurrent, *ch.current)
+		}
+	}
+}
+
+// setSeriesGauge выставляет значение серии с двумя лейблами и продлевает её TTL.
+func (c *PrometheusCollector) setSeriesGauge(gauge *prometheus.GaugeVec, nodeID, labelValue, metricName string, value float64) {
+	gauge.WithLabelValues(nodeID, labelValue).Set(value)
+	c.updateMetricTimestamp(nodeID, seriesKey(metricName, labelValue))
+}
+
+// seriesKey ключ серии с дополнительным лейблом для TTL и файла состояния.
+func seriesKey(metricName, labelValue string) string {
+	return metricName + seriesKeySeparator + labelValue
 }
 
 func (c *PrometheusCollector) setNetworkMetrics(data domain.TelemetryData) {
@@ -203,21 +810,85 @@ func (c *PrometheusCollector) setNetworkMetrics(data domain.TelemetryData) {
 	if data.AirUtilTx != nil {
 		c.airUtilTx.WithLabelValues(data.NodeID).Set(*data.AirUtilTx)
 	}
-	if data.RSSI != nil {
-		c.rssi.WithLabelValues(data.NodeID).Set(*data.RSSI)
+}
+
+func (c *PrometheusCollector) setSignalMetrics(nodeID, gatewayID string, rssi, snr *float64) {
+	if gatewayID == "" {
+		gatewayID = unknownValue
+	}
+	if rssi != nil {
+		c.setSeriesGauge(c.rssi, nodeID, gatewayID, domain.MetricRSSI, *rssi)
+	}
+	if snr != nil {
+		c.setSeriesGauge(c.snr, nodeID, gatewayID, domain.MetricSNR, *snr)
+	}
+}
+
+// CollectReception учитывает приём пакета шлюзом, вызывается для каждого сообщения.
+func (c *PrometheusCollector) CollectReception(r domain.Reception) {
+	gatewayID := r.GatewayID
+	if gatewayID == "" {
+		gatewayID = unknownValue
+	}
+	c.addCounter(c.gatewayPackets, domain.MetricGatewayPackets, 1, gatewayID, r.MessageType, r.Region, r.Channel)
+	c.setSignalMetrics(r.NodeID, gatewayID, r.RSSI, r.SNR)
+	if !r.RxTime.IsZero() {
+		c.observeRxTime(gatewayID, r.RxTime, time.Now())
+	}
+}
+
+// observeRxTime разность rx_time и текущего времени складывается из задержки доставки
+// и сдвига часов шлюза. Задержка не бывает отрицательной, поэтому максимум разности
+// за окно — оценка сдвига по самому быстро доставленному пакету.
+func (c *PrometheusCollector) observeRxTime(gatewayID string, rxTime, now time.Time) {
+	offset := rxTime.Sub(now).Seconds()
+	c.ingestLatency.WithLabelValues(gatewayID).Observe(math.Max(0, -offset))
+	c.updateMetricTimestamp(gatewayID, domain.MetricIngestLatency)
+
+	c.mu.Lock()
+	window, exists := c.clockSkew[gatewayID]
+	if !exists {
+		window = &skewWindow{}
+		c.clockSkew[gatewayID] = window
 	}
-	if data.SNR != nil {
-		c.snr.WithLabelValues(data.NodeID).Set(*data.SNR)
+	skew := window.observe(offset, now)
+	c.mu.Unlock()
+
+	c.gatewaySkew.WithLabelValues(gatewayID).Set(skew)
+	c.updateMetricTimestamp(gatewayID, domain.MetricGatewayClockSkew)
+}
+
+func (c *PrometheusCollector) UpdateDuplicateCounter(gatewayID string) {
+	if gatewayID == "" {
+		gatewayID = unknownValue
 	}
+	c.addCounter(c.duplicates, domain.MetricDuplicatePackets, 1, gatewayID)
 }
 
 func (c *PrometheusCollector) CollectNodeInfo(info domain.NodeInfo) error {
-	c.UpdateNodeLastSeen(info.NodeID, time.Now())
+	c.UpdateNodeLastSeen(info.NodeID, observedAt(info.Timestamp))
 	c.UpdateMessageCounter(info.NodeID, domain.MessageTypeNodeInfo)
-	c.nodeHardware.WithLabelValues(info.NodeID, info.LongName, info.ShortName, info.Hardware, info.Role).Set(1)
+	c.nodeHardware.WithLabelValues(info.NodeID, info.LongName, info.ShortName, info.Hardware, info.HWModel, info.Role).Set(1)
+	c.setNodeModel(info.NodeID, info.HWModel)
 	return nil
 }
 
+// setNodeModel пересчитывает meshtastic_nodes_by_hardware при смене модели ноды.
+func (c *PrometheusCollector) setNodeModel(nodeID, model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, known := c.nodeModels[nodeID]
+	if known && previous == model {
+		return
+	}
+	if known {
+		c.nodesByHW.WithLabelValues(previous).Dec()
+	}
+	c.nodeModels[nodeID] = model
+	c.nodesByHW.WithLabelValues(model).Inc()
+}
+
 func (c *PrometheusCollector) CollectTextMessage(msg domain.TextMessage) error {
 	c.UpdateNodeLastSeen(msg.NodeID, time.Now())
 	c.UpdateMessageCounter(msg.NodeID, domain.MessageTypeText)
@@ -225,11 +896,128 @@ func (c *PrometheusCollector) CollectTextMessage(msg domain.TextMessage) error {
 }
 
 func (c *PrometheusCollector) CollectPosition(pos domain.Position) error {
-	c.UpdateNodeLastSeen(pos.NodeID, time.Now())
+	c.UpdateNodeLastSeen(pos.NodeID, observedAt(pos.Timestamp))
 	c.UpdateMessageCounter(pos.NodeID, domain.MessageTypePosition)
+	c.setPositionMetrics(pos)
 	return nil
 }
 
+func (c *PrometheusCollector) setPositionMetrics(pos domain.Position) {
+	if pos.Latitude != nil && pos.Longitude != nil {
+		c.setNodeGauge(c.posLatitude, pos.NodeID, domain.MetricPositionLatitude, *pos.Latitude)
+		c.setNodeGauge(c.posLongitude, pos.NodeID, domain.MetricPositionLongitude, *pos.Longitude)
+		c.rememberPosition(pos.NodeID, *pos.Latitude, *pos.Longitude)
+	}
+	if pos.Altitude != nil {
+		c.setNodeGauge(c.posAltitude, pos.NodeID, domain.MetricPositionAltitude, float64(*pos.Altitude))
+	}
+	if pos.SatsInView != nil {
+		c.setNodeGauge(c.posSatsInView, pos.NodeID, domain.MetricPositionSatsInView, float64(*pos.SatsInView))
+	}
+	if pos.PrecisionBits != nil {
+		c.setNodeGauge(c.posPrecisionBits, pos.NodeID, domain.MetricPositionPrecisionBits, float64(*pos.PrecisionBits))
+	}
+}
+
+func (c *PrometheusCollector) rememberPosition(nodeID string, latitude, longitude float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.positions[nodeID] = coordinates{latitude: latitude, longitude: longitude}
+}
+
+// distance расстояние между последними известными позициями двух нод.
+func (c *PrometheusCollector) distance(nodeID, otherID string) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	from, ok := c.positions[nodeID]
+	if !ok {
+		return 0, false
+	}
+	to, ok := c.positions[otherID]
+	if !ok {
+		return 0, false
+	}
+	return domain.Distance(from.latitude, from.longitude, to.latitude, to.longitude), true
+}
+
+func (c *PrometheusCollector) CollectMapReport(report domain.MapReport) error {
+	c.UpdateNodeLastSeen(report.NodeID, observedAt(report.Timestamp))
+	c.UpdateMessageCounter(report.NodeID, domain.MessageTypeMapReport)
+
+	c.setFirmwareInfo(report.NodeID, firmwareInfo{
+		version:     report.FirmwareVersion,
+		region:      report.Region,
+		modemPreset: report.ModemPreset,
+	})
+	c.setNodeGauge(c.defaultChannel, report.NodeID, domain.MetricNodeDefaultChannel, boolToFloat(report.HasDefaultChannel))
+	if report.OnlineLocalNodes != nil {
+		c.setNodeGauge(c.onlineLocalNodes, report.NodeID, domain.MetricNodeOnlineLocalNodes, float64(*report.OnlineLocalNodes))
+	}
+	c.setPositionMetrics(report.Position)
+	return nil
+}
+
+// setFirmwareInfo заменяет серию meshtastic_node_firmware_info и пересчитывает
+// meshtastic_nodes_by_firmware, когда нода обновила прошивку или сменила настройки.
+func (c *PrometheusCollector) setFirmwareInfo(nodeID string, info firmwareInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, known := c.nodeFirmware[nodeID]
+	if known && previous == info {
+		return
+	}
+	if known {
+		c.firmwareInfo.DeleteLabelValues(nodeID, previous.version, previous.region, previous.modemPreset)
+		c.nodesByFirmware.WithLabelValues(previous.version).Dec()
+	}
+	c.nodeFirmware[nodeID] = info
+	c.firmwareInfo.WithLabelValues(nodeID, info.version, info.region, info.modemPreset).Set(1)
+	c.nodesByFirmware.WithLabelValues(info.version).Inc()
+}
+
+// setNodeGauge выставляет значение и продлевает TTL метрики ноды.
+func (c *PrometheusCollector) setNodeGauge(gauge *prometheus.GaugeVec, nodeID, metricName string, value float64) {
+	gauge.WithLabelValues(nodeID).Set(value)
+	c.updateMetricTimestamp(nodeID, metricName)
+}
+
+// addCounter увеличивает счётчик и продлевает TTL серий по первому лейблу.
+func (c *PrometheusCollector) addCounter(counter *prometheus.CounterVec, metricName string, value float64, labelValues ...string) {
+	counter.WithLabelValues(labelValues...).Add(value)
+	c.updateMetricTimestamp(labelValues[0], metricName)
+}
+
+// clearNodeGauge удаляет серию, значение которой больше не определено.
+func (c *PrometheusCollector) clearNodeGauge(gauge *prometheus.GaugeVec, nodeID, metricName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gauge.DeleteLabelValues(nodeID)
+	delete(c.metricTimestamps[nodeID], metricName)
+}
+
+func (c *PrometheusCollector) setBatteryEstimate(data domain.TelemetryData) {
+	estimate := data.Battery
+	if estimate == nil {
+		return
+	}
+	c.setNodeGauge(c.batterySoC, data.NodeID, domain.MetricBatterySoC, estimate.StateOfCharge)
+	c.setNodeGauge(c.batteryPower, data.NodeID, domain.MetricBatteryExternalPower, boolToFloat(estimate.ExternalPower))
+	c.setNodeGauge(c.batteryCharge, data.NodeID, domain.MetricBatteryCharging, boolToFloat(estimate.Charging))
+	if estimate.TimeToEmpty == nil {
+		c.clearNodeGauge(c.batteryTTE, data.NodeID, domain.MetricBatteryTimeToEmpty)
+		return
+	}
+	c.setNodeGauge(c.batteryTTE, data.NodeID, domain.MetricBatteryTimeToEmpty, estimate.TimeToEmpty.Seconds())
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
 func (c *PrometheusCollector) CollectWaypoint(wp domain.Waypoint) error {
 	c.UpdateNodeLastSeen(wp.NodeID, time.Now())
 	c.UpdateMessageCounter(wp.NodeID, domain.MessageTypeWaypoint)
@@ -237,21 +1025,196 @@ func (c *PrometheusCollector) CollectWaypoint(wp domain.Waypoint) error {
 }
 
 func (c *PrometheusCollector) CollectNeighborInfo(ni domain.NeighborInfo) error {
-	c.UpdateNodeLastSeen(ni.NodeID, time.Now())
+	c.UpdateNodeLastSeen(ni.NodeID, observedAt(ni.Timestamp))
 	c.UpdateMessageCounter(ni.NodeID, domain.MessageTypeNeighborInfo)
+
+	for _, neighbor := range ni.Neighbors {
+		c.setSeriesGauge(c.neighborSNR, ni.NodeID, neighbor.NeighborID, domain.MetricNeighborSNR, neighbor.SNR)
+		if neighbor.LastRxTime > 0 {
+			c.setSeriesGauge(c.neighborLastRx, ni.NodeID, neighbor.NeighborID, domain.MetricNeighborLastRx, float64(neighbor.LastRxTime))
+		}
+
+		interval := neighbor.NodeBroadcastIntervalSecs
+		if interval == 0 {
+			interval = ni.NodeBroadcastIntervalSecs
+		}
+		if interval > 0 {
+			c.setSeriesGauge(c.neighborBroadcastInterval, ni.NodeID, neighbor.NeighborID, domain.MetricNeighborBroadcastInterval, float64(interval))
+		}
+	}
+	return nil
+}
+
+// CollectTraceroute хопы выставляются как рёбра from -> to, TTL каждого ребра считается от ноды from.
+func (c *PrometheusCollector) CollectTraceroute(tr domain.Traceroute) error {
+	tr.Timestamp = observedAt(tr.Timestamp)
+	c.UpdateNodeLastSeen(tr.Destination, tr.Timestamp)
+	c.UpdateMessageCounter(tr.Destination, domain.MessageTypeTraceroute)
+
+	for _, hop := range tr.Hops {
+		c.setSeriesGauge(c.hopSNR, hop.From, hop.To, domain.MetricTracerouteHopSNR, hop.SNR)
+	}
+	c.setRoute(tr)
 	return nil
 }
 
+// setRoute заменяет последний маршрут между парой нод.
+func (c *PrometheusCollector) setRoute(tr domain.Traceroute) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := seriesKey(tr.Origin, tr.Destination)
+	if previous, exists := c.traceroute[key]; exists {
+		c.routeInfo.DeleteLabelValues(previous.Origin, previous.Destination, formatRoute(previous.Route), formatRoute(previous.RouteBack))
+	}
+	c.traceroute[key] = tr
+
+	c.routeInfo.WithLabelValues(tr.Origin, tr.Destination, formatRoute(tr.Route), formatRoute(tr.RouteBack)).Set(1)
+	c.routeHops.WithLabelValues(tr.Origin, tr.Destination, domain.TracerouteTowards).Set(float64(len(tr.Route) - 1))
+	// неполный обратный путь не показываем, чтобы не занижать число хопов
+	if n := len(tr.RouteBack); n > 1 && tr.RouteBack[n-1] == tr.Origin {
+		c.routeHops.WithLabelValues(tr.Origin, tr.Destination, domain.TracerouteBack).Set(float64(n - 1))
+	} else {
+		c.routeHops.DeleteLabelValues(tr.Origin, tr.Destination, domain.TracerouteBack)
+	}
+}
+
+func formatRoute(nodes []string) string {
+	return strings.Join(nodes, ">")
+}
+
+// Traceroutes последние трассировки по парам нод, новые первыми.
+func (c *PrometheusCollector) Traceroutes() []domain.Traceroute {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]domain.Traceroute, 0, len(c.traceroute))
+	for _, tr := range c.traceroute {
+		if c.nodeIDFormat == domain.NodeIDFormatBoth {
+			tr.OriginNum, _ = domain.ParseNodeID(tr.Origin)
+			tr.DestinationNum, _ = domain.ParseNodeID(tr.Destination)
+		}
+		result = append(result, tr)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Timestamp.After(result[j].Timestamp)
+	})
+	return result
+}
+
+// expireTraceroutes удаляет маршруты старше TTL, вызывается под c.mu.
+func (c *PrometheusCollector) expireTraceroutes(now time.Time) {
+	for key, tr := range c.traceroute {
+		if now.Sub(tr.Timestamp) <= c.metricsTTL {
+			continue
+		}
+		c.routeInfo.DeleteLabelValues(tr.Origin, tr.Destination, formatRoute(tr.Route), formatRoute(tr.RouteBack))
+		c.routeHops.DeleteLabelValues(tr.Origin, tr.Destination, domain.TracerouteTowards)
+		c.routeHops.DeleteLabelValues(tr.Origin, tr.Destination, domain.TracerouteBack)
+		delete(c.traceroute, key)
+	}
+}
+
+// UpdateNodeLastSeen не отодвигает время назад: копии и задержанные пакеты приходят с более ранним rx_time.
 func (c *PrometheusCollector) UpdateNodeLastSeen(nodeID string, timestamp time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if timestamp.Before(c.lastSeen[nodeID]) {
+		return
+	}
+	c.lastSeen[nodeID] = timestamp
 	c.nodeLastSeen.WithLabelValues(nodeID).Set(float64(timestamp.Unix()))
 }
 
+func observedAt(timestamp time.Time) time.Time {
+	if timestamp.IsZero() {
+		return time.Now()
+	}
+	return timestamp
+}
+
 func (c *PrometheusCollector) UpdateMessageCounter(nodeID string, messageType string) {
 	c.messageCounter.WithLabelValues(messageType, nodeID).Inc()
 }
 
+func (c *PrometheusCollector) UpdateUndecryptableCounter(channel string) {
+	c.undecryptable.WithLabelValues(channel).Inc()
+}
+
+func (c *PrometheusCollector) CollectPacketHops(nodeID string, hopsAway int) {
+	c.packetHops.Observe(float64(hopsAway))
+	c.setNodeGauge(c.nodeHops, nodeID, domain.MetricNodeHops, float64(hopsAway))
+}
+
+func (c *PrometheusCollector) UpdateRelayCounter(relayNode string) {
+	c.addCounter(c.relayPackets, domain.MetricRelayPacket, 1, relayNode)
+}
+
+func (c *PrometheusCollector) CollectRoutingResult(result domain.RoutingResult) error {
+	c.UpdateNodeLastSeen(result.NodeID, observedAt(result.Timestamp))
+	c.UpdateMessageCounter(result.NodeID, domain.MessageTypeRouting)
+	if result.Reason == domain.RoutingErrorNone {
+		c.addCounter(c.routingAcks, domain.MetricRoutingAcks, 1, result.NodeID)
+		return nil
+	}
+	c.addCounter(c.routingErrors, domain.MetricRoutingErrors, 1, result.NodeID, result.Reason)
+	return nil
+}
+
+func (c *PrometheusCollector) CollectDetection(event domain.DetectionEvent) error {
+	c.UpdateNodeLastSeen(event.NodeID, observedAt(event.Timestamp))
+	c.UpdateMessageCounter(event.NodeID, domain.MessageTypeDetection)
+	if event.Triggered {
+		c.addCounter(c.detectionEvents, domain.MetricDetectionEvents, 1, event.NodeID, event.Sensor)
+	}
+	c.setSeriesGauge(c.detectionState, event.NodeID, event.Sensor, domain.MetricDetectionState, event.State)
+	return nil
+}
+
+func (c *PrometheusCollector) CollectPaxcount(pax domain.Paxcount) error {
+	c.UpdateNodeLastSeen(pax.NodeID, observedAt(pax.Timestamp))
+	c.UpdateMessageCounter(pax.NodeID, domain.MessageTypePaxcounter)
+	c.setNodeGauge(c.paxWiFi, pax.NodeID, domain.MetricPaxcounterWiFi, float64(pax.WiFi))
+	c.setNodeGauge(c.paxBLE, pax.NodeID, domain.MetricPaxcounterBLE, float64(pax.BLE))
+	return nil
+}
+
+// CollectRangeTest результат range test для пары отправитель — шлюз. Расстояние
+// считается по последним позициям обеих нод, если шлюз тоже публикует свою позицию.
+func (c *PrometheusCollector) CollectRangeTest(packet domain.RangeTestPacket) error {
+	c.UpdateNodeLastSeen(packet.NodeID, observedAt(packet.Timestamp))
+	c.UpdateMessageCounter(packet.NodeID, domain.MessageTypeRangeTest)
+
+	gatewayID := packet.GatewayID
+	if gatewayID == "" {
+		gatewayID = unknownValue
+	}
+	c.addCounter(c.rangeTestPackets, domain.MetricRangeTestPackets, 1, packet.NodeID, gatewayID)
+	c.setSeriesGauge(c.rangeSequence, packet.NodeID, gatewayID, domain.MetricRangeTestSequence, float64(packet.Sequence))
+	if packet.RSSI != nil {
+		c.setSeriesGauge(c.rangeRSSI, packet.NodeID, gatewayID, domain.MetricRangeTestRSSI, *packet.RSSI)
+	}
+	if packet.SNR != nil {
+		c.setSeriesGauge(c.rangeSNR, packet.NodeID, gatewayID, domain.MetricRangeTestSNR, *packet.SNR)
+	}
+	// gateway_id всегда в hex, позиции хранятся под node_id в настроенном формате
+	if meters, ok := c.distance(packet.NodeID, domain.ConvertNodeID(gatewayID, c.nodeIDFormat)); ok {
+		c.setSeriesGauge(c.rangeDistance, packet.NodeID, gatewayID, domain.MetricRangeTestDistance, meters)
+	}
+	return nil
+}
+
+func (c *PrometheusCollector) CollectDelivery(stats domain.DeliveryStats) {
+	c.addCounter(c.packetsExpected, domain.MetricPacketsExpected, float64(stats.Expected), stats.NodeID)
+	c.addCounter(c.packetsReceived, domain.MetricPacketsReceived, float64(stats.Received), stats.NodeID)
+	if stats.Missed > 0 {
+		c.addCounter(c.packetGaps, domain.MetricPacketGaps, 1, stats.NodeID, stats.Stream)
+	}
+	c.setNodeGauge(c.deliveryRatio, stats.NodeID, domain.MetricDeliveryRatio, stats.Ratio)
+}
+
 func (c *PrometheusCollector) GetRegistry() *prometheus.Registry {
-	return c.registry
+	return c.exposed
 }
 
 func (c *PrometheusCollector) SaveState(filename string) error {
@@ -285,6 +1248,9 @@ func (c *PrometheusCollector) extractNodeMetrics(metricFamilies []*dto.MetricFam
 	nodeMetrics := make(map[string]domain.MetricState)
 
 	for _, mf := range metricFamilies {
+		if !c.persisted(mf.GetName()) {
+			continue
+		}
 		for _, metric := range mf.GetMetric() {
 			nodeID, labels := c.extractLabels(metric)
 			if nodeID == "" {
@@ -299,6 +1265,24 @@ func (c *PrometheusCollector) extractNodeMetrics(metricFamilies []*dto.MetricFam
 	return nodeMetrics
 }
 
+// persisted метрики, которые restoreMetric умеет восстановить: скаляры ноды,
+// seriesGauges с persist и info-метрики. Счётчики с лейблами кроме node_id
+// (messages_total, routing_errors_total, ...) в файл не пишутся: в ключе по имени
+// метрики их серии затирали бы друг друга.
+func (c *PrometheusCollector) persisted(metricName string) bool {
+	if _, exists := c.nodeGauges[metricName]; exists {
+		return true
+	}
+	if series, exists := c.seriesGauges[metricName]; exists {
+		return series.persist
+	}
+	switch metricName {
+	case domain.MetricNodeReboots, domain.MetricNodeInfo, domain.MetricNodeFirmwareInfo:
+		return true
+	}
+	return false
+}
+
 func (c *PrometheusCollector) extractLabels(metric *dto.Metric) (string, map[string]string) {
 	var nodeID string
 	labels := make(map[string]string)
@@ -326,13 +1310,18 @@ func (c *PrometheusCollector) ensureNodeState(nodeMetrics map[string]domain.Metr
 
 func (c *PrometheusCollector) updateNodeMetric(nodeMetrics map[string]domain.MetricState, nodeID, metricName string, metric *dto.Metric, labels map[string]string) {
 	nodeState := nodeMetrics[nodeID]
+	if series, exists := c.seriesGauges[metricName]; exists {
+		nodeState.Metrics[seriesKey(metricName, labels[series.label])] = c.extractMetricValue(metric)
+		nodeMetrics[nodeID] = nodeState
+		return
+	}
 	nodeState.Metrics[metricName] = c.extractMetricValue(metric)
 	c.updateNodeLabels(&nodeState, metricName, nodeID, labels)
 	nodeMetrics[nodeID] = nodeState
 }
 
 func (c *PrometheusCollector) updateNodeLabels(nodeState *domain.MetricState, metricName, nodeID string, labels map[string]string) {
-	if metricName == domain.MetricNodeInfo {
+	if metricName == domain.MetricNodeInfo || metricName == domain.MetricNodeFirmwareInfo {
 		for k, v := range labels {
 			nodeState.Labels[k] = v
 		}
@@ -404,57 +1393,150 @@ func (c *PrometheusCollector) readStateFile(filename string) (*domain.StateSnaps
 }
 
 func (c *PrometheusCollector) restoreMetrics(nodes []domain.MetricState) {
+	migrated := 0
 	for _, nodeState := range nodes {
+		// файл мог быть сохранён с другим node_id_format
+		if nodeID := domain.ConvertNodeID(nodeState.NodeID, c.nodeIDFormat); nodeID != nodeState.NodeID {
+			c.mu.Lock()
+			c.forgetNode(nodeState.NodeID)
+			c.mu.Unlock()
+			nodeState.NodeID = nodeID
+			migrated++
+		}
 		for metricName, value := range nodeState.Metrics {
 			c.restoreMetric(metricName, value, nodeState)
 		}
+		c.restorePosition(nodeState)
+	}
+
+	if migrated > 0 {
+		log := logger.ComponentLogger(metricsCollectorComponent)
+		log.Info().Int("nodes", migrated).Str("format", c.nodeIDFormat).Msg("node ids migrated to configured format")
 	}
 }
 
+// forgetNode удаляет все серии и состояние ноды, вызывается под c.mu.
+func (c *PrometheusCollector) forgetNode(nodeID string) {
+	for _, metric := range c.nodeVecs {
+		metric.vec.DeletePartialMatch(prometheus.Labels{metric.label: nodeID})
+	}
+	if model, known := c.nodeModels[nodeID]; known {
+		c.nodesByHW.WithLabelValues(model).Dec()
+		delete(c.nodeModels, nodeID)
+	}
+	if firmware, known := c.nodeFirmware[nodeID]; known {
+		c.nodesByFirmware.WithLabelValues(firmware.version).Dec()
+		delete(c.nodeFirmware, nodeID)
+	}
+	delete(c.metricTimestamps, nodeID)
+	delete(c.lastUptime, nodeID)
+	delete(c.lastSeen, nodeID)
+	delete(c.positions, nodeID)
+}
+
 func (c *PrometheusCollector) restoreMetric(metricName string, value float64, nodeState domain.MetricState) {
-	metricMap := map[string]*prometheus.GaugeVec{
-		domain.MetricBatteryLevel: c.batteryLevel,
-		domain.MetricVoltage:      c.voltage,
-		domain.MetricTemperature:  c.temperature,
-		domain.MetricHumidity:     c.humidity,
-		domain.MetricPressure:     c.pressure,
-		domain.MetricChannelUtil:  c.channelUtil,
-		domain.MetricAirUtilTx:    c.airUtilTx,
-		domain.MetricUptime:       c.uptime,
-		domain.MetricRSSI:         c.rssi,
-		domain.MetricSNR:          c.snr,
-		domain.MetricNodeLastSeen: c.nodeLastSeen,
-	}
-
-	if gauge, exists := metricMap[metricName]; exists {
+	if gauge, exists := c.nodeGauges[metricName]; exists {
 		gauge.WithLabelValues(nodeState.NodeID).Set(value)
+		c.restoreUptime(metricName, value, nodeState)
+	} else if metricName == domain.MetricNodeReboots && value > 0 {
+		c.nodeReboots.WithLabelValues(nodeState.NodeID).Add(value)
+	} else if c.restoreSeries(metricName, value, nodeState.NodeID) {
+		return
 	} else if metricName == domain.MetricNodeInfo {
-		longname := nodeState.Labels["longname"]
-		if longname == "" {
-			longname = unknownValue
-		}
-		shortname := nodeState.Labels["shortname"]
-		if shortname == "" {
-			shortname = unknownValue
-		}
-		hardware := nodeState.Labels["hardware"]
-		if hardware == "" {
-			hardware = unknownValue
-		}
-		role := nodeState.Labels["role"]
-		if role == "" {
-			role = unknownValue
+		c.restoreNodeInfo(value, nodeState)
+	} else if metricName == domain.MetricNodeFirmwareInfo {
+		c.setFirmwareInfo(nodeState.NodeID, firmwareInfo{
+			version:     labelOrUnknown(nodeState.Labels, "firmware_version"),
+			region:      labelOrUnknown(nodeState.Labels, "region"),
+			modemPreset: labelOrUnknown(nodeState.Labels, "modem_preset"),
+		})
+	}
+}
+
+// restoreSeries восстанавливает серию с дополнительным лейблом, false — метрика не серия.
+// В файлах до появления gateway_id RSSI и SNR сохранены без шлюза, они восстанавливаются
+// с gateway_id="unknown" и удаляются по TTL после загрузки: шлюз unknown их не обновит.
+func (c *PrometheusCollector) restoreSeries(metricName string, value float64, nodeID string) bool {
+	name, labelValue, isSeries := strings.Cut(metricName, seriesKeySeparator)
+	series, exists := c.seriesGauges[name]
+	if !isSeries {
+		if !exists || series.label != "gateway_id" {
+			return false
 		}
-		c.nodeHardware.WithLabelValues(nodeState.NodeID, longname, shortname, hardware, role).Set(value)
+		labelValue = unknownValue
+		c.updateMetricTimestamp(nodeID, seriesKey(name, labelValue))
+	}
+	if exists {
+		series.vec.WithLabelValues(nodeID, labelValue).Set(value)
+	}
+	return true
+}
+
+// restorePosition координаты для расстояния range test, чтобы не ждать следующей позиции ноды.
+func (c *PrometheusCollector) restorePosition(nodeState domain.MetricState) {
+	latitude, hasLatitude := nodeState.Metrics[domain.MetricPositionLatitude]
+	longitude, hasLongitude := nodeState.Metrics[domain.MetricPositionLongitude]
+	if hasLatitude && hasLongitude {
+		c.rememberPosition(nodeState.NodeID, latitude, longitude)
+	}
+}
+
+// restoreUptime запоминает сохранённый uptime, чтобы перезагрузку во время простоя экспортера тоже посчитать.
+// Время выборки — last seen ноды, в файлах без него — время сохранения.
+func (c *PrometheusCollector) restoreUptime(metricName string, value float64, nodeState domain.MetricState) {
+	if metricName != domain.MetricUptime {
+		return
+	}
+	at := time.Unix(nodeState.Timestamp, 0)
+	if lastSeen, ok := nodeState.Metrics[domain.MetricNodeLastSeen]; ok && lastSeen > 0 {
+		at = time.Unix(int64(lastSeen), 0)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastUptime[nodeState.NodeID] = uptimeSample{uptime: value, at: at}
+}
+
+func (c *PrometheusCollector) restoreNodeInfo(value float64, nodeState domain.MetricState) {
+	hardware := labelOrUnknown(nodeState.Labels, "hardware")
+	// в файлах состояния старых версий hw_model нет, восстанавливаем по номеру модели
+	hwModel := nodeState.Labels["hw_model"]
+	if hwModel == "" {
+		hwModel = domain.HardwareModelFromLabel(hardware)
+	}
+
+	c.nodeHardware.WithLabelValues(
+		nodeState.NodeID,
+		labelOrUnknown(nodeState.Labels, "longname"),
+		labelOrUnknown(nodeState.Labels, "shortname"),
+		hardware,
+		hwModel,
+		labelOrUnknown(nodeState.Labels, "role"),
+	).Set(value)
+	c.setNodeModel(nodeState.NodeID, hwModel)
+}
+
+func labelOrUnknown(labels map[string]string, name string) string {
+	if value := labels[name]; value != "" {
+		return value
 	}
+	return unknownValue
 }
 func (c *PrometheusCollector) updateMetricTimestamp(nodeID, metricName string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.metricTimestamps[nodeID] == nil {
-		c.metricTimestamps[nodeID] = make(map[string]time.Time)
+	timestamps := c.timestampsFor(metricName)
+	if timestamps[nodeID] == nil {
+		timestamps[nodeID] = make(map[string]time.Time)
 	}
-	c.metricTimestamps[nodeID][metricName] = time.Now()
+	timestamps[nodeID][metricName] = time.Now()
+}
+
+// timestampsFor карта TTL метрики: по шлюзам и ретрансляторам или по нодам.
+func (c *PrometheusCollector) timestampsFor(metricName string) map[string]map[string]time.Time {
+	if metric, exists := c.expiringVecs[metricName]; exists && metric.label != "node_id" {
+		return c.gatewayTimestamps
+	}
+	return c.metricTimestamps
 }
 
 func (c *PrometheusCollector) startMetricsTTLCleanup() {
@@ -489,28 +1571,46 @@ func (c *PrometheusCollector) cleanupExpiredMetrics() {
 	defer c.mu.Unlock()
 
 	now := time.Now()
+	c.expireTimestamps(c.metricTimestamps, now)
+	c.expireTimestamps(c.gatewayTimestamps, now)
+	c.expireTraceroutes(now)
+}
 
-	for nodeID, metrics := range c.metricTimestamps {
+func (c *PrometheusCollector) expireTimestamps(timestamps map[string]map[string]time.Time, now time.Time) {
+	for key, metrics := range timestamps {
 		for metricName, timestamp := range metrics {
 			if now.Sub(timestamp) > c.metricsTTL {
-				c.deleteMetric(nodeID, metricName)
+				c.deleteMetric(key, metricName)
 				delete(metrics, metricName)
 			}
 		}
 		if len(metrics) == 0 {
-			delete(c.metricTimestamps, nodeID)
+			delete(timestamps, key)
 		}
 	}
 }
 
 func (c *PrometheusCollector) deleteMetric(nodeID, metricName string) {
-	switch metricName {
-	case domain.MetricTemperature:
-		c.temperature.DeleteLabelValues(nodeID)
-	case domain.MetricHumidity:
-		c.humidity.DeleteLabelValues(nodeID)
-	case domain.MetricPressure:
-		c.pressure.DeleteLabelValues(nodeID)
+	if metric, exists := c.expiringVecs[metricName]; exists {
+		metric.vec.DeletePartialMatch(prometheus.Labels{metric.label: nodeID})
+		if metricName == domain.MetricGatewayClockSkew {
+			delete(c.clockSkew, nodeID)
+		}
+		return
+	}
+	if name, labelValue, isSeries := strings.Cut(metricName, seriesKeySeparator); isSeries {
+		if series, exists := c.seriesGauges[name]; exists {
+			series.vec.DeleteLabelValues(nodeID, labelValue)
+		} else {
+			c.deleteMappedSeries(nodeID, name, labelValue)
+		}
+		return
+	}
+	if gauge, exists := c.nodeGauges[metricName]; exists {
+		gauge.DeleteLabelValues(nodeID)
+	}
+	if metricName == domain.MetricPositionLatitude {
+		delete(c.positions, nodeID)
 	}
 }
 
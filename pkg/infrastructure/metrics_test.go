@@ -6,12 +6,22 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"meshtastic-exporter/pkg/domain"
 )
 
+func TestPrometheusCollector_OptionalInterfaces(t *testing.T) {
+	t.Parallel()
+	var collector domain.MetricsCollector = NewPrometheusCollector()
+
+	assert.Implements(t, (*domain.PacketStatsCollector)(nil), collector)
+	assert.Implements(t, (*domain.ModuleCollector)(nil), collector)
+	assert.Implements(t, (*domain.MappedValuesCollector)(nil), collector)
+}
+
 func TestPrometheusCollector_CollectTelemetry(t *testing.T) {
 	t.Parallel()
 	collector := NewPrometheusCollector()
@@ -47,6 +57,7 @@ func TestPrometheusCollector_CollectNodeInfo(t *testing.T) {
 		LongName:  "Test Node",
 		ShortName: "TN01",
 		Hardware:  "1",
+		HWModel:   "TLORA_V2",
 		Role:      "2",
 		Timestamp: time.Now(),
 	}
@@ -55,7 +66,7 @@ func TestPrometheusCollector_CollectNodeInfo(t *testing.T) {
 
 	require.NoError(t, err)
 
-	nodeInfoMetric := testutil.ToFloat64(collector.nodeHardware.WithLabelValues("987654321", "Test Node", "TN01", "1", "2"))
+	nodeInfoMetric := testutil.ToFloat64(collector.nodeHardware.WithLabelValues("987654321", "Test Node", "TN01", "1", "TLORA_V2", "2"))
 	assert.Equal(t, 1.0, nodeInfoMetric)
 }
 
@@ -121,8 +132,8 @@ func TestPrometheusCollector_AllTelemetryFields(t *testing.T) {
 	expectedMetrics := []string{
 		domain.MetricBatteryLevel,
 		domain.MetricTemperature,
-		domain.MetricRSSI,
-		domain.MetricSNR,
+		domain.MetricChannelUtil,
+		domain.MetricAirUtilTx,
 		domain.MetricVoltage,
 		domain.MetricHumidity,
 		domain.MetricPressure,
@@ -131,6 +142,8 @@ func TestPrometheusCollector_AllTelemetryFields(t *testing.T) {
 	for _, metric := range expectedMetrics {
 		assert.True(t, metricNames[metric], "Metric %s not found", metric)
 	}
+	assert.False(t, metricNames[domain.MetricRSSI])
+	assert.False(t, metricNames[domain.MetricSNR])
 }
 
 func TestSetBasicMetrics(t *testing.T) {
@@ -190,13 +203,678 @@ func TestSetNetworkMetrics(t *testing.T) {
 
 	collector.setNetworkMetrics(data)
 
-	rssiMetric := testutil.ToFloat64(collector.rssi.WithLabelValues("123"))
-	assert.Equal(t, -90.0, rssiMetric)
+	assert.Equal(t, 15.0, testutil.ToFloat64(collector.channelUtil.WithLabelValues("123")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(collector.airUtilTx.WithLabelValues("123")))
+	// сигнал пишет только CollectReception
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.rssi))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.snr))
+}
+
+func TestPrometheusCollector_CollectReception(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	collector.CollectReception(domain.Reception{NodeID: "123", GatewayID: "!aaaa0001", MessageType: domain.MessageTypeText, RSSI: floatPtr(-80), SNR: floatPtr(9.5)})
+	collector.CollectReception(domain.Reception{NodeID: "123", GatewayID: "!aaaa0002", MessageType: domain.MessageTypeText, RSSI: floatPtr(-110), SNR: floatPtr(-4)})
+	collector.CollectReception(domain.Reception{NodeID: "456", GatewayID: "!aaaa0001", Region: "EU_868", Channel: "LongFast", MessageType: domain.MessageTypePosition})
+	collector.CollectReception(domain.Reception{NodeID: "789", MessageType: domain.MessageTypeText})
+
+	assert.InDelta(t, -80, testutil.ToFloat64(collector.rssi.WithLabelValues("123", "!aaaa0001")), 0.001)
+	assert.InDelta(t, -110, testutil.ToFloat64(collector.rssi.WithLabelValues("123", "!aaaa0002")), 0.001)
+	assert.InDelta(t, -4, testutil.ToFloat64(collector.snr.WithLabelValues("123", "!aaaa0002")), 0.001)
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.rssi))
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.gatewayPackets.WithLabelValues("!aaaa0001", domain.MessageTypeText, "", "")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.gatewayPackets.WithLabelValues("!aaaa0001", domain.MessageTypePosition, "EU_868", "LongFast")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.gatewayPackets.WithLabelValues(unknownValue, domain.MessageTypeText, "", "")), 0.001)
 
-	snrMetric := testutil.ToFloat64(collector.snr.WithLabelValues("123"))
-	assert.Equal(t, -5.0, snrMetric)
+	collector.mu.Lock()
+	collector.metricTimestamps["123"][seriesKey(domain.MetricRSSI, "!aaaa0002")] = time.Now().Add(-2 * time.Hour)
+	collector.mu.Unlock()
+
+	collector.cleanupExpiredMetrics()
+
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.rssi))
+}
+
+func TestPrometheusCollector_CounterTTL(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: domain.RoutingErrorNone}))
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: "NO_ROUTE"}))
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "456", Reason: "NO_ROUTE"}))
+	collector.UpdateDuplicateCounter("!aaaa0001")
+	collector.UpdateDuplicateCounter("!aaaa0002")
+	collector.UpdateRelayCounter("0x54")
+	collector.CollectReception(domain.Reception{NodeID: "123", GatewayID: "!aaaa0001", MessageType: domain.MessageTypeText, RxTime: time.Now()})
+
+	expired := time.Now().Add(-2 * time.Hour)
+	collector.mu.Lock()
+	collector.metricTimestamps["123"][domain.MetricRoutingAcks] = expired
+	collector.metricTimestamps["123"][domain.MetricRoutingErrors] = expired
+	for metricName := range collector.gatewayTimestamps["!aaaa0001"] {
+		collector.gatewayTimestamps["!aaaa0001"][metricName] = expired
+	}
+	collector.gatewayTimestamps["0x54"][domain.MetricRelayPacket] = expired
+	collector.mu.Unlock()
+
+	collector.cleanupExpiredMetrics()
+
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.routingAcks))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.routingErrors))
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.routingErrors.WithLabelValues("456", "NO_ROUTE")), 0.001)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.duplicates))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.gatewayPackets))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.ingestLatency))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.gatewaySkew))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.relayPackets))
+	collector.mu.RLock()
+	assert.NotContains(t, collector.clockSkew, "!aaaa0001")
+	collector.mu.RUnlock()
+}
+
+func TestPrometheusCollector_ForgetNodeKeepsGateway(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithOptions(CollectorOptions{NodeIDFormat: domain.NodeIDFormatHex})
+	defer collector.Shutdown()
+
+	// в формате hex шлюз и его нода — одна строка
+	collector.CollectReception(domain.Reception{NodeID: "!aaaa0002", GatewayID: "!aaaa0001", MessageType: domain.MessageTypeText, RxTime: time.Now()})
+	collector.UpdateNodeLastSeen("!aaaa0001", time.Now())
+
+	collector.mu.Lock()
+	collector.forgetNode("!aaaa0001")
+	collector.mu.Unlock()
+
+	collector.mu.RLock()
+	defer collector.mu.RUnlock()
+	assert.NotContains(t, collector.metricTimestamps, "!aaaa0001")
+	assert.Contains(t, collector.gatewayTimestamps["!aaaa0001"], domain.MetricGatewayPackets)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.gatewayPackets))
+}
+
+func TestPrometheusCollector_UndecryptableCounter(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+
+	collector.UpdateUndecryptableCounter("LongFast")
+	collector.UpdateUndecryptableCounter("LongFast")
+	collector.UpdateUndecryptableCounter("Private")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.undecryptable.WithLabelValues("LongFast")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.undecryptable.WithLabelValues("Private")))
+}
+
+func TestPrometheusCollector_CollectPosition(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+
+	altitude, sats, precision := int32(150), int32(8), int32(13)
+	pos := domain.Position{
+		NodeID:        "123",
+		Latitude:      floatPtr(55.9748544),
+		Longitude:     floatPtr(37.3418112),
+		Altitude:      &altitude,
+		SatsInView:    &sats,
+		PrecisionBits: &precision,
+		Timestamp:     time.Now(),
+	}
+
+	err := collector.CollectPosition(pos)
+	require.NoError(t, err)
+
+	assert.Equal(t, 55.9748544, testutil.ToFloat64(collector.posLatitude.WithLabelValues("123")))
+	assert.Equal(t, 37.3418112, testutil.ToFloat64(collector.posLongitude.WithLabelValues("123")))
+	assert.Equal(t, 150.0, testutil.ToFloat64(collector.posAltitude.WithLabelValues("123")))
+	assert.Equal(t, 8.0, testutil.ToFloat64(collector.posSatsInView.WithLabelValues("123")))
+	assert.Equal(t, 13.0, testutil.ToFloat64(collector.posPrecisionBits.WithLabelValues("123")))
+}
+
+func TestPrometheusCollector_PositionTTLCleanup(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	err := collector.CollectPosition(domain.Position{NodeID: "123", Latitude: floatPtr(1.5), Longitude: floatPtr(2.5)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.posLatitude))
+
+	collector.mu.Lock()
+	collector.metricTimestamps["123"][domain.MetricPositionLatitude] = time.Now().Add(-2 * time.Hour)
+	collector.metricTimestamps["123"][domain.MetricPositionLongitude] = time.Now().Add(-2 * time.Hour)
+	collector.mu.Unlock()
+
+	collector.cleanupExpiredMetrics()
+
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.posLatitude))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.posLongitude))
 }
 
 func floatPtr(f float64) *float64 {
 	return &f
 }
+
+func TestPrometheusCollector_CollectNeighborInfo(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	err := collector.CollectNeighborInfo(domain.NeighborInfo{
+		NodeID:                    "123",
+		NodeBroadcastIntervalSecs: 900,
+		Neighbors: []domain.Neighbor{
+			{NeighborID: "456", SNR: 6.5, LastRxTime: 1640995200},
+			{NeighborID: "789", SNR: -2.75, NodeBroadcastIntervalSecs: 300},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.InDelta(t, 6.5, testutil.ToFloat64(collector.neighborSNR.WithLabelValues("123", "456")), 0.001)
+	assert.InDelta(t, -2.75, testutil.ToFloat64(collector.neighborSNR.WithLabelValues("123", "789")), 0.001)
+	assert.InDelta(t, 1640995200, testutil.ToFloat64(collector.neighborLastRx.WithLabelValues("123", "456")), 0.001)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.neighborLastRx))
+	assert.InDelta(t, 900, testutil.ToFloat64(collector.neighborBroadcastInterval.WithLabelValues("123", "456")), 0.001)
+	assert.InDelta(t, 300, testutil.ToFloat64(collector.neighborBroadcastInterval.WithLabelValues("123", "789")), 0.001)
+}
+
+func TestPrometheusCollector_NeighborEdgeTTL(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	err := collector.CollectNeighborInfo(domain.NeighborInfo{
+		NodeID:    "123",
+		Neighbors: []domain.Neighbor{{NeighborID: "456", SNR: 6.5}, {NeighborID: "789", SNR: 3}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.neighborSNR))
+
+	collector.mu.Lock()
+	collector.metricTimestamps["123"][seriesKey(domain.MetricNeighborSNR, "456")] = time.Now().Add(-2 * time.Hour)
+	collector.mu.Unlock()
+
+	collector.cleanupExpiredMetrics()
+
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.neighborSNR))
+	assert.InDelta(t, 3, testutil.ToFloat64(collector.neighborSNR.WithLabelValues("123", "789")), 0.001)
+}
+
+func TestPrometheusCollector_SensorMetricsTTLCleanup(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	err := collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:        "123",
+		GasResistance: floatPtr(12.5),
+		IAQ:           floatPtr(55),
+		Ch1Voltage:    floatPtr(13.2),
+		Ch2Voltage:    floatPtr(5.1),
+		Ch2Current:    floatPtr(120),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.powerVoltage))
+
+	expired := time.Now().Add(-2 * time.Hour)
+	collector.mu.Lock()
+	collector.metricTimestamps["123"][domain.MetricGasResistance] = expired
+	collector.metricTimestamps["123"][domain.MetricIAQ] = expired
+	collector.metricTimestamps["123"][seriesKey(domain.MetricPowerVoltage, "1")] = expired
+	collector.metricTimestamps["123"][seriesKey(domain.MetricPowerCurrent, "2")] = expired
+	collector.mu.Unlock()
+
+	collector.cleanupExpiredMetrics()
+
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.gasResistance))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.iaq))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.powerVoltage))
+	assert.InDelta(t, 5.1, testutil.ToFloat64(collector.powerVoltage.WithLabelValues("123", "2")), 0.001)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.powerCurrent))
+}
+
+func TestPrometheusCollector_SensorFields(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	err := collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:  "123",
+		Type:    domain.TelemetryTypeAirQuality,
+		Sensors: map[string]float64{domain.MetricPM25Standard: 12, domain.MetricCO2: 640},
+	})
+	require.NoError(t, err)
+
+	assert.InDelta(t, 12, testutil.ToFloat64(collector.sensorGauges[domain.MetricPM25Standard].WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 640, testutil.ToFloat64(collector.sensorGauges[domain.MetricCO2].WithLabelValues("123")), 0.001)
+
+	collector.mu.Lock()
+	collector.metricTimestamps["123"][domain.MetricCO2] = time.Now().Add(-2 * time.Hour)
+	collector.mu.Unlock()
+
+	collector.cleanupExpiredMetrics()
+
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.sensorGauges[domain.MetricCO2]))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.sensorGauges[domain.MetricPM25Standard]))
+}
+
+func TestPrometheusCollector_CollectPacketHops(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("hook", time.Hour)
+	defer collector.Shutdown()
+
+	collector.CollectPacketHops("123", 2)
+	collector.CollectPacketHops("123", 0)
+	collector.CollectPacketHops("456", 3)
+	collector.UpdateRelayCounter("0x54")
+	collector.UpdateRelayCounter("0x54")
+
+	assert.InDelta(t, 0, testutil.ToFloat64(collector.nodeHops.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 3, testutil.ToFloat64(collector.nodeHops.WithLabelValues("456")), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.relayPackets.WithLabelValues("0x54")), 0.001)
+
+	metric := &dto.Metric{}
+	require.NoError(t, collector.packetHops.Write(metric))
+	assert.Equal(t, uint64(3), metric.GetHistogram().GetSampleCount())
+	assert.InDelta(t, 5, metric.GetHistogram().GetSampleSum(), 0.001)
+}
+
+func TestPrometheusCollector_UpdateDuplicateCounter(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	collector.UpdateDuplicateCounter("!aaaa0002")
+	collector.UpdateDuplicateCounter("!aaaa0002")
+	collector.UpdateDuplicateCounter("")
+
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.duplicates.WithLabelValues("!aaaa0002")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.duplicates.WithLabelValues(unknownValue)), 0.001)
+}
+
+func TestPrometheusCollector_NodesByHardware(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	nodes := []domain.NodeInfo{
+		{NodeID: "1", Hardware: "43", HWModel: "HELTEC_V3"},
+		{NodeID: "2", Hardware: "43", HWModel: "HELTEC_V3"},
+		{NodeID: "3", Hardware: "9", HWModel: "RAK4631"},
+		{NodeID: "1", Hardware: "43", HWModel: "HELTEC_V3"},
+		{NodeID: "2", Hardware: "9", HWModel: "RAK4631"},
+	}
+	for _, info := range nodes {
+		require.NoError(t, collector.CollectNodeInfo(info))
+	}
+
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodesByHW.WithLabelValues("HELTEC_V3")), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.nodesByHW.WithLabelValues("RAK4631")), 0.001)
+}
+
+func TestPrometheusCollector_RestoreNodeInfoWithoutHWModel(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	collector.restoreMetrics([]domain.MetricState{{
+		NodeID:  "123",
+		Metrics: map[string]float64{domain.MetricNodeInfo: 1},
+		Labels:  map[string]string{"longname": "Rooftop", "shortname": "RT", "hardware": "43", "role": "router"},
+	}})
+
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodeHardware.WithLabelValues("123", "Rooftop", "RT", "43", "HELTEC_V3", "router")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodesByHW.WithLabelValues("HELTEC_V3")), 0.001)
+}
+
+func TestPrometheusCollector_RestoreSignalWithoutGateway(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	// файл состояния до появления gateway_id
+	collector.restoreMetrics([]domain.MetricState{{
+		NodeID:  "123",
+		Metrics: map[string]float64{domain.MetricRSSI: -95, domain.MetricSNR: 6.5, domain.MetricPowerVoltage: 12},
+	}})
+
+	assert.InDelta(t, -95, testutil.ToFloat64(collector.rssi.WithLabelValues("123", unknownValue)), 0.001)
+	assert.InDelta(t, 6.5, testutil.ToFloat64(collector.snr.WithLabelValues("123", unknownValue)), 0.001)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.powerVoltage))
+	assert.Contains(t, collector.metricTimestamps["123"], seriesKey(domain.MetricRSSI, unknownValue))
+}
+
+func TestPrometheusCollector_DetectReboot(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	for _, uptime := range []float64{3600, 7200, 120, 300} {
+		require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "123", UptimeSeconds: floatPtr(uptime)}))
+	}
+
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodeReboots.WithLabelValues("123")), 0.001)
+	expectedBoot := float64(time.Now().Add(-120 * time.Second).Unix())
+	assert.InDelta(t, expectedBoot, testutil.ToFloat64(collector.nodeLastReboot.WithLabelValues("123")), 2)
+
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "123", UptimeSeconds: floatPtr(10)}))
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.nodeReboots.WithLabelValues("123")), 0.001)
+}
+
+func TestPrometheusCollector_DetectRebootUsesPacketTime(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	uptime := func(seconds float64, at time.Time) {
+		require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "123", UptimeSeconds: floatPtr(seconds), Timestamp: at}))
+	}
+
+	uptime(3600, start)
+	// пакет от другого шлюза старше последнего учтённого
+	uptime(1800, start.Add(-30*time.Minute))
+	// падение меньше прошедшего времени — поздняя доставка, а не перезагрузка
+	uptime(3000, start.Add(20*time.Minute))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.nodeReboots))
+
+	uptime(60, start.Add(30*time.Minute))
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodeReboots.WithLabelValues("123")), 0.001)
+	expectedBoot := float64(start.Add(29 * time.Minute).Unix())
+	assert.InDelta(t, expectedBoot, testutil.ToFloat64(collector.nodeLastReboot.WithLabelValues("123")), 0.001)
+}
+
+func TestPrometheusCollector_NoRebootOnFirstUptime(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "123", UptimeSeconds: floatPtr(60)}))
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "456", UptimeSeconds: floatPtr(10)}))
+
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.nodeReboots))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.nodeLastReboot))
+}
+
+func TestPrometheusCollector_BatteryEstimate(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	timeToEmpty := 6 * time.Hour
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:  "123",
+		Battery: &domain.BatteryEstimate{StateOfCharge: 60, TimeToEmpty: &timeToEmpty},
+	}))
+
+	assert.InDelta(t, 60, testutil.ToFloat64(collector.batterySoC.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 0, testutil.ToFloat64(collector.batteryPower.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 21600, testutil.ToFloat64(collector.batteryTTE.WithLabelValues("123")), 0.001)
+
+	// на внешнем питании прогноз разряда не имеет смысла, серия удаляется
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:  "123",
+		Battery: &domain.BatteryEstimate{StateOfCharge: 100, ExternalPower: true, Charging: true},
+	}))
+
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.batteryPower.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.batteryCharge.WithLabelValues("123")), 0.001)
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.batteryTTE))
+	collector.mu.RLock()
+	assert.NotContains(t, collector.metricTimestamps["123"], domain.MetricBatteryTimeToEmpty)
+	collector.mu.RUnlock()
+}
+
+func TestPrometheusCollector_CollectDelivery(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	collector.CollectDelivery(domain.DeliveryStats{NodeID: "123", Stream: domain.TelemetryTypeDevice, Expected: 1, Received: 1, Ratio: 1})
+	collector.CollectDelivery(domain.DeliveryStats{NodeID: "123", Stream: domain.TelemetryTypeDevice, Expected: 4, Received: 1, Missed: 3, Ratio: 0.4})
+
+	assert.InDelta(t, 5, testutil.ToFloat64(collector.packetsExpected.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.packetsReceived.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.packetGaps.WithLabelValues("123", domain.TelemetryTypeDevice)), 0.001)
+	assert.InDelta(t, 0.4, testutil.ToFloat64(collector.deliveryRatio.WithLabelValues("123")), 0.001)
+}
+
+func TestPrometheusCollector_CollectMapReport(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	latitude, longitude, onlineNodes := 55.75, 37.62, int32(17)
+	report := domain.MapReport{
+		NodeID:            "123",
+		FirmwareVersion:   "2.5.15.79da236",
+		Region:            "RU",
+		ModemPreset:       "LONG_FAST",
+		HasDefaultChannel: true,
+		OnlineLocalNodes:  &onlineNodes,
+		Position:          domain.Position{NodeID: "123", Latitude: &latitude, Longitude: &longitude},
+	}
+	require.NoError(t, collector.CollectMapReport(report))
+	require.NoError(t, collector.CollectMapReport(domain.MapReport{NodeID: "456", FirmwareVersion: "2.5.15.79da236"}))
+
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.firmwareInfo.WithLabelValues("123", "2.5.15.79da236", "RU", "LONG_FAST")), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.nodesByFirmware.WithLabelValues("2.5.15.79da236")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.defaultChannel.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 17, testutil.ToFloat64(collector.onlineLocalNodes.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 55.75, testutil.ToFloat64(collector.posLatitude.WithLabelValues("123")), 0.001)
+
+	// после обновления прошивки старая серия удаляется
+	report.FirmwareVersion = "2.6.4.b89355f"
+	require.NoError(t, collector.CollectMapReport(report))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.firmwareInfo))
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodesByFirmware.WithLabelValues("2.5.15.79da236")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.nodesByFirmware.WithLabelValues("2.6.4.b89355f")), 0.001)
+}
+
+func TestPrometheusCollector_CollectTraceroute(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithTTL("test", time.Minute)
+	defer collector.Shutdown()
+
+	tr := domain.Traceroute{
+		Origin:      "1",
+		Destination: "3",
+		Route:       []string{"1", "2", "3"},
+		RouteBack:   []string{"3", "1"},
+		Hops: []domain.TracerouteHop{
+			{From: "1", To: "2", Direction: domain.TracerouteTowards, SNR: 6.25},
+			{From: "2", To: "3", Direction: domain.TracerouteTowards, SNR: -4},
+			{From: "3", To: "1", Direction: domain.TracerouteBack, SNR: -11.5},
+		},
+	}
+	require.NoError(t, collector.CollectTraceroute(tr))
+
+	assert.InDelta(t, 6.25, testutil.ToFloat64(collector.hopSNR.WithLabelValues("1", "2")), 0.001)
+	assert.InDelta(t, -11.5, testutil.ToFloat64(collector.hopSNR.WithLabelValues("3", "1")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.routeInfo.WithLabelValues("1", "3", "1>2>3", "3>1")), 0.001)
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.routeHops.WithLabelValues("1", "3", domain.TracerouteTowards)), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.routeHops.WithLabelValues("1", "3", domain.TracerouteBack)), 0.001)
+
+	// новый маршрут заменяет старый, неполный обратный путь не считается
+	tr.Route = []string{"1", "3"}
+	tr.RouteBack = []string{"3", "4"}
+	tr.Hops = nil
+	require.NoError(t, collector.CollectTraceroute(tr))
+
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.routeInfo))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.routeHops))
+	require.Len(t, collector.Traceroutes(), 1)
+	assert.Equal(t, []string{"1", "3"}, collector.Traceroutes()[0].Route)
+
+	collector.mu.Lock()
+	collector.expireTraceroutes(time.Now().Add(2 * time.Minute))
+	collector.mu.Unlock()
+
+	assert.Empty(t, collector.Traceroutes())
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.routeInfo))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.routeHops))
+}
+
+func TestPrometheusCollector_TracerouteNodeNums(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithOptions(CollectorOptions{NodeIDFormat: domain.NodeIDFormatBoth})
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectTraceroute(domain.Traceroute{
+		Origin:      "!0000000a",
+		Destination: "!f992bd54",
+		Route:       []string{"!0000000a", "!f992bd54"},
+	}))
+
+	require.Len(t, collector.Traceroutes(), 1)
+	assert.Equal(t, uint32(10), collector.Traceroutes()[0].OriginNum)
+	assert.Equal(t, uint32(4187143508), collector.Traceroutes()[0].DestinationNum)
+}
+
+// seriesLabels лейблы серий семейства metricName из реестра коллектора.
+func seriesLabels(t *testing.T, collector *PrometheusCollector, metricName string) []map[string]string {
+	t.Helper()
+	families, err := collector.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	var series []map[string]string
+	for _, family := range families {
+		if family.GetName() != metricName {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			series = append(series, labels)
+		}
+	}
+	return series
+}
+
+func TestPrometheusCollector_RegistryNodeNum(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollectorWithOptions(CollectorOptions{NodeIDFormat: domain.NodeIDFormatBoth})
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "!f992bd54", BatteryLevel: floatPtr(80)}))
+	require.NoError(t, collector.CollectNodeInfo(domain.NodeInfo{NodeID: "!f992bd54", LongName: "Test Node"}))
+	collector.CollectReception(domain.Reception{NodeID: "!f992bd54", GatewayID: "!0000000a", RSSI: floatPtr(-90)})
+	collector.UpdateRelayCounter("0x54")
+	require.NoError(t, collector.CollectTraceroute(domain.Traceroute{
+		Origin:      "!0000000a",
+		Destination: "!f992bd54",
+		Route:       []string{"!0000000a", "!f992bd54"},
+		Hops:        []domain.TracerouteHop{{From: "!0000000a", To: "!f992bd54", SNR: 6}},
+	}))
+
+	tests := []struct {
+		metric string
+		want   map[string]string
+	}{
+		{metric: domain.MetricBatteryLevel, want: map[string]string{"node_num": "4187143508"}},
+		{metric: domain.MetricNodeInfo, want: map[string]string{"node_num": "4187143508"}},
+		{metric: domain.MetricMessagesTotal, want: map[string]string{"node_num": "4187143508"}},
+		{metric: domain.MetricRSSI, want: map[string]string{"node_num": "4187143508", "gateway_num": "10"}},
+		{metric: domain.MetricRelayPacket, want: map[string]string{"relay_node_num": "84"}},
+		{metric: domain.MetricTracerouteHopSNR, want: map[string]string{"from_num": "10", "to_num": "4187143508"}},
+		{metric: domain.MetricTracerouteRoute, want: map[string]string{"origin_num": "10", "destination_num": "4187143508"}},
+		{metric: domain.MetricTracerouteHops, want: map[string]string{"origin_num": "10", "destination_num": "4187143508"}},
+	}
+	for _, tt := range tests {
+		series := seriesLabels(t, collector, tt.metric)
+		require.NotEmpty(t, series, tt.metric)
+		for _, labels := range series {
+			for name, value := range tt.want {
+				assert.Equal(t, value, labels[name], "%s %s", tt.metric, name)
+			}
+		}
+	}
+
+	// исходный реестр для файла состояния без номеров
+	families, err := collector.registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				assert.NotEqual(t, "node_num", label.GetName(), family.GetName())
+			}
+		}
+	}
+}
+
+func TestPrometheusCollector_RegistryWithoutNodeNum(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "4187143508", BatteryLevel: floatPtr(80)}))
+
+	assert.Same(t, collector.registry, collector.GetRegistry())
+	for _, labels := range seriesLabels(t, collector, domain.MetricBatteryLevel) {
+		assert.NotContains(t, labels, "node_num")
+	}
+}
+
+func TestPrometheusCollector_CollectRoutingResult(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: domain.RoutingErrorNone, RequestID: 1}))
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: domain.RoutingErrorNone, RequestID: 2}))
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: "DUTY_CYCLE_LIMIT", RequestID: 3}))
+
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.routingAcks.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.routingErrors.WithLabelValues("123", "DUTY_CYCLE_LIMIT")), 0.001)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.routingErrors))
+}
+
+func TestPrometheusCollector_CollectDetection(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectDetection(domain.DetectionEvent{NodeID: "123", Sensor: "Door", Triggered: true, State: 1}))
+	require.NoError(t, collector.CollectDetection(domain.DetectionEvent{NodeID: "123", Sensor: "Door", State: 0}))
+
+	assert.InDelta(t, 1, testutil.ToFloat64(collector.detectionEvents.WithLabelValues("123", "Door")), 0.001)
+	assert.InDelta(t, 0, testutil.ToFloat64(collector.detectionState.WithLabelValues("123", "Door")), 0.001)
+}
+
+func TestPrometheusCollector_CollectPaxcount(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectPaxcount(domain.Paxcount{NodeID: "123", WiFi: 12, BLE: 30}))
+
+	assert.InDelta(t, 12, testutil.ToFloat64(collector.paxWiFi.WithLabelValues("123")), 0.001)
+	assert.InDelta(t, 30, testutil.ToFloat64(collector.paxBLE.WithLabelValues("123")), 0.001)
+}
+
+func TestPrometheusCollector_CollectRangeTest(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	rssi, snr := -112.0, -7.5
+	packet := domain.RangeTestPacket{NodeID: "123", GatewayID: "!000001c8", Sequence: 41, RSSI: &rssi, SNR: &snr}
+	require.NoError(t, collector.CollectRangeTest(packet))
+
+	assert.InDelta(t, 41, testutil.ToFloat64(collector.rangeSequence.WithLabelValues("123", "!000001c8")), 0.001)
+	assert.InDelta(t, -112, testutil.ToFloat64(collector.rangeRSSI.WithLabelValues("123", "!000001c8")), 0.001)
+	assert.InDelta(t, -7.5, testutil.ToFloat64(collector.rangeSNR.WithLabelValues("123", "!000001c8")), 0.001)
+	// позиции шлюза ещё нет
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.rangeDistance))
+
+	lat, lon, gatewayLat := 60.0, 30.0, 60.01
+	require.NoError(t, collector.CollectPosition(domain.Position{NodeID: "123", Latitude: &lat, Longitude: &lon}))
+	require.NoError(t, collector.CollectPosition(domain.Position{NodeID: "456", Latitude: &gatewayLat, Longitude: &lon}))
+	packet.Sequence = 42
+	require.NoError(t, collector.CollectRangeTest(packet))
+
+	assert.InDelta(t, 2, testutil.ToFloat64(collector.rangeTestPackets.WithLabelValues("123", "!000001c8")), 0.001)
+	assert.InDelta(t, 1111.95, testutil.ToFloat64(collector.rangeDistance.WithLabelValues("123", "!000001c8")), 0.01)
+}
@@ -0,0 +1,90 @@
+package infrastructure
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+// nodeNumLabels лейблы с ID нод и десятичные лейблы, которые к ним добавляются
+// в формате both.
+var nodeNumLabels = map[string]string{
+	"node_id":     "node_num",
+	"from_node":   "node_num",
+	"neighbor_id": "neighbor_num",
+	"gateway_id":  "gateway_num",
+	"from":        "from_num",
+	"to":          "to_num",
+	"origin":      "origin_num",
+	"destination": "destination_num",
+	"relay_node":  "relay_node_num",
+}
+
+// nodeNumCollector отдаёт метрики реестра коллектора с десятичными номерами нод.
+// Регистрируется в реестре из GetRegistry, поэтому номера есть при любом способе
+// сбора: /metrics, свой HTTP обработчик встраивающего приложения, Gather().
+// Describe ничего не отдаёт: набор лейблов у серий разный, реестр их не сверяет.
+type nodeNumCollector struct {
+	gatherer prometheus.Gatherer
+}
+
+func (nodeNumCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (n nodeNumCollector) Collect(ch chan<- prometheus.Metric) {
+	families, err := n.gatherer.Gather()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(prometheus.NewDesc("meshtastic_gather_error", "Collector gather error", nil, nil), err)
+	}
+	for _, family := range families {
+		desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), nil, nil)
+		for _, metric := range family.GetMetric() {
+			ch <- gatheredMetric{desc: desc, metric: metric, labels: withNodeNums(metric.GetLabel())}
+		}
+	}
+}
+
+// gatheredMetric уже собранная серия с дополненными лейблами.
+type gatheredMetric struct {
+	desc   *prometheus.Desc
+	metric *dto.Metric
+	labels []*dto.LabelPair
+}
+
+func (m gatheredMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+func (m gatheredMetric) Write(out *dto.Metric) error {
+	out.Label = m.labels
+	out.Gauge = m.metric.Gauge
+	out.Counter = m.metric.Counter
+	out.Summary = m.metric.Summary
+	out.Untyped = m.metric.Untyped
+	out.Histogram = m.metric.Histogram
+	out.TimestampMs = m.metric.TimestampMs
+	return nil
+}
+
+// withNodeNums добавляет десятичный номер к каждому лейблу с ID ноды из nodeNumLabels.
+// Нераспознанные ID (gateway_id="unknown") остаются без номера.
+func withNodeNums(labels []*dto.LabelPair) []*dto.LabelPair {
+	result := append([]*dto.LabelPair(nil), labels...)
+	for _, label := range labels {
+		name, ok := nodeNumLabels[label.GetName()]
+		if !ok {
+			continue
+		}
+		nodeNum, err := domain.ParseNodeID(label.GetValue())
+		if err != nil {
+			continue
+		}
+		value := strconv.FormatUint(uint64(nodeNum), 10)
+		result = append(result, &dto.LabelPair{Name: &name, Value: &value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GetName() < result[j].GetName() })
+	return result
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -56,6 +57,27 @@ func TestPrometheusCollector_StatePersistence(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestPrometheusCollector_StateSkipsMultiLabelCounters(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "123", BatteryLevel: floatPtr(80)}))
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: "NO_ROUTE"}))
+	require.NoError(t, collector.CollectRoutingResult(domain.RoutingResult{NodeID: "123", Reason: "TIMEOUT"}))
+	require.NoError(t, collector.CollectDetection(domain.DetectionEvent{NodeID: "123", Sensor: "door", Triggered: true, State: 1}))
+
+	families, err := collector.registry.Gather()
+	require.NoError(t, err)
+	metrics := collector.extractNodeMetrics(families)["123"].Metrics
+
+	assert.Contains(t, metrics, domain.MetricBatteryLevel)
+	assert.Contains(t, metrics, seriesKey(domain.MetricDetectionState, "door"))
+	assert.NotContains(t, metrics, domain.MetricRoutingErrors)
+	assert.NotContains(t, metrics, domain.MetricDetectionEvents)
+	assert.NotContains(t, metrics, domain.MetricMessagesTotal)
+}
+
 func TestPrometheusCollector_StateCorruptedFile(t *testing.T) {
 	t.Parallel()
 	collector := NewPrometheusCollector()
@@ -152,3 +174,145 @@ func TestPrometheusCollector_StateLargeDataset(t *testing.T) {
 	err = newCollector.LoadState(tempFile)
 	require.NoError(t, err)
 }
+
+func TestPrometheusCollector_StatePersistenceSensorMetrics(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	err := collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:        "123456789",
+		GasResistance: floatPtr(12.5),
+		IAQ:           floatPtr(55),
+		Ch1Voltage:    floatPtr(13.2),
+		Ch1Current:    floatPtr(250),
+		Ch3Voltage:    floatPtr(5.1),
+	})
+	require.NoError(t, err)
+
+	tempFile := filepath.Join(t.TempDir(), "sensor_state.json")
+	require.NoError(t, collector.SaveState(tempFile))
+
+	newCollector := NewPrometheusCollector()
+	defer newCollector.Shutdown()
+	require.NoError(t, newCollector.LoadState(tempFile))
+
+	assert.InDelta(t, 12.5, testutil.ToFloat64(newCollector.gasResistance.WithLabelValues("123456789")), 0.001)
+	assert.InDelta(t, 55, testutil.ToFloat64(newCollector.iaq.WithLabelValues("123456789")), 0.001)
+	assert.InDelta(t, 13.2, testutil.ToFloat64(newCollector.powerVoltage.WithLabelValues("123456789", "1")), 0.001)
+	assert.InDelta(t, 5.1, testutil.ToFloat64(newCollector.powerVoltage.WithLabelValues("123456789", "3")), 0.001)
+	assert.InDelta(t, 250, testutil.ToFloat64(newCollector.powerCurrent.WithLabelValues("123456789", "1")), 0.001)
+	assert.Equal(t, 2, testutil.CollectAndCount(newCollector.powerVoltage))
+}
+
+func TestPrometheusCollector_StateNodeIDMigration(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{
+		NodeID:       "4187143508",
+		BatteryLevel: floatPtr(77),
+		Ch1Voltage:   floatPtr(12.1),
+	}))
+	require.NoError(t, collector.CollectNodeInfo(domain.NodeInfo{
+		NodeID:    "4187143508",
+		LongName:  "Test Node",
+		ShortName: "TN01",
+		Hardware:  "43",
+		HWModel:   "HELTEC_V3",
+		Role:      "client",
+	}))
+
+	tempFile := filepath.Join(t.TempDir(), "decimal_state.json")
+	require.NoError(t, collector.SaveState(tempFile))
+
+	hexCollector := NewPrometheusCollectorWithOptions(CollectorOptions{NodeIDFormat: domain.NodeIDFormatBoth})
+	defer hexCollector.Shutdown()
+	require.NoError(t, hexCollector.LoadState(tempFile))
+
+	assert.InDelta(t, 77, testutil.ToFloat64(hexCollector.batteryLevel.WithLabelValues("!f992bd54")), 0.001)
+	assert.InDelta(t, 12.1, testutil.ToFloat64(hexCollector.powerVoltage.WithLabelValues("!f992bd54", "1")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(hexCollector.nodeHardware.WithLabelValues(
+		"!f992bd54", "Test Node", "TN01", "43", "HELTEC_V3", "client")), 0.001)
+	assert.Equal(t, 1, testutil.CollectAndCount(hexCollector.batteryLevel))
+
+	// обратная миграция в decimal
+	hexFile := filepath.Join(t.TempDir(), "hex_state.json")
+	require.NoError(t, hexCollector.SaveState(hexFile))
+
+	decimalCollector := NewPrometheusCollector()
+	defer decimalCollector.Shutdown()
+	require.NoError(t, decimalCollector.LoadState(hexFile))
+	assert.InDelta(t, 77, testutil.ToFloat64(decimalCollector.batteryLevel.WithLabelValues("4187143508")), 0.001)
+}
+
+func TestPrometheusCollector_StateMigrationDropsOldSeries(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+	require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "4187143508", BatteryLevel: floatPtr(77)}))
+
+	tempFile := filepath.Join(t.TempDir(), "decimal_state.json")
+	require.NoError(t, collector.SaveState(tempFile))
+
+	// серии в старом формате, которые появились до загрузки состояния
+	hexCollector := NewPrometheusCollectorWithOptions(CollectorOptions{NodeIDFormat: domain.NodeIDFormatHex})
+	defer hexCollector.Shutdown()
+	hexCollector.UpdateMessageCounter("4187143508", domain.MessageTypeText)
+	require.NoError(t, hexCollector.CollectRoutingResult(domain.RoutingResult{NodeID: "4187143508", Reason: domain.RoutingErrorNone}))
+	require.NoError(t, hexCollector.CollectNodeInfo(domain.NodeInfo{NodeID: "4187143508", HWModel: "HELTEC_V3"}))
+
+	require.NoError(t, hexCollector.LoadState(tempFile))
+
+	assert.InDelta(t, 77, testutil.ToFloat64(hexCollector.batteryLevel.WithLabelValues("!f992bd54")), 0.001)
+	assert.Equal(t, 0, testutil.CollectAndCount(hexCollector.messageCounter))
+	assert.Equal(t, 0, testutil.CollectAndCount(hexCollector.routingAcks))
+	assert.Equal(t, 0, testutil.CollectAndCount(hexCollector.nodeHardware))
+	assert.InDelta(t, 0, testutil.ToFloat64(hexCollector.nodesByHW.WithLabelValues("HELTEC_V3")), 0.001)
+}
+
+func TestPrometheusCollector_StatePersistenceReboots(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	for _, uptime := range []float64{500, 20, 1000, 30} {
+		require.NoError(t, collector.CollectTelemetry(domain.TelemetryData{NodeID: "123456789", UptimeSeconds: floatPtr(uptime)}))
+	}
+	lastReboot := testutil.ToFloat64(collector.nodeLastReboot.WithLabelValues("123456789"))
+
+	tempFile := filepath.Join(t.TempDir(), "reboot_state.json")
+	require.NoError(t, collector.SaveState(tempFile))
+
+	newCollector := NewPrometheusCollector()
+	defer newCollector.Shutdown()
+	require.NoError(t, newCollector.LoadState(tempFile))
+
+	assert.InDelta(t, 2, testutil.ToFloat64(newCollector.nodeReboots.WithLabelValues("123456789")), 0.001)
+	assert.InDelta(t, lastReboot, testutil.ToFloat64(newCollector.nodeLastReboot.WithLabelValues("123456789")), 0.001)
+
+	// перезагрузка, пока экспортер не работал, видна по восстановленному uptime
+	require.NoError(t, newCollector.CollectTelemetry(domain.TelemetryData{NodeID: "123456789", UptimeSeconds: floatPtr(5)}))
+	assert.InDelta(t, 3, testutil.ToFloat64(newCollector.nodeReboots.WithLabelValues("123456789")), 0.001)
+}
+
+func TestPrometheusCollector_StatePersistenceFirmwareInfo(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+
+	require.NoError(t, collector.CollectNodeInfo(domain.NodeInfo{NodeID: "123456789", LongName: "Base", ShortName: "BS", Hardware: "43", HWModel: "HELTEC_V3", Role: "router"}))
+	require.NoError(t, collector.CollectMapReport(domain.MapReport{NodeID: "123456789", FirmwareVersion: "2.5.15.79da236", Region: "EU_868", ModemPreset: "LONG_FAST"}))
+
+	tempFile := filepath.Join(t.TempDir(), "firmware_state.json")
+	require.NoError(t, collector.SaveState(tempFile))
+
+	newCollector := NewPrometheusCollector()
+	defer newCollector.Shutdown()
+	require.NoError(t, newCollector.LoadState(tempFile))
+
+	assert.InDelta(t, 1, testutil.ToFloat64(newCollector.firmwareInfo.WithLabelValues("123456789", "2.5.15.79da236", "EU_868", "LONG_FAST")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(newCollector.nodesByFirmware.WithLabelValues("2.5.15.79da236")), 0.001)
+	assert.InDelta(t, 1, testutil.ToFloat64(newCollector.nodeHardware.WithLabelValues("123456789", "Base", "BS", "43", "HELTEC_V3", "router")), 0.001)
+}
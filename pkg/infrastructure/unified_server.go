@@ -87,6 +87,10 @@ func (s *UnifiedServer) Start(ctx context.Context) error {
 		mux.HandleFunc(s.config.AlertPath, s.alertWebhookHandler)
 	}
 
+	if store, ok := s.collector.(domain.TracerouteStore); ok {
+		mux.HandleFunc(domain.DefaultTraceroutesPath, s.traceroutesHandler(store))
+	}
+
 	handler := middleware.ChainMiddleware(
 		middleware.RecoveryMiddleware(s.logger),
 		middleware.TimeoutMiddleware(domain.DefaultTimeout),
@@ -137,6 +141,19 @@ func (s *UnifiedServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"service":"meshtastic-exporter","status":"ok","timestamp":%d}`, time.Now().Unix())
 }
 
+func (s *UnifiedServer) traceroutesHandler(store domain.TracerouteStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(store.Traceroutes()); err != nil {
+			s.logger.Error().Err(err).Msg("failed to encode traceroutes")
+		}
+	}
+}
+
 func (s *UnifiedServer) alertWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"meshtastic-exporter/pkg/domain"
 	"meshtastic-exporter/pkg/mocks"
 )
 
@@ -57,6 +58,31 @@ func TestUnifiedServer_HealthHandler(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "ok")
 }
 
+func TestUnifiedServer_TraceroutesHandler(t *testing.T) {
+	t.Parallel()
+	collector := NewPrometheusCollector()
+	defer collector.Shutdown()
+	require.NoError(t, collector.CollectTraceroute(domain.Traceroute{
+		Origin:      "1",
+		Destination: "3",
+		Route:       []string{"1", "2", "3"},
+		Hops:        []domain.TracerouteHop{{From: "1", To: "2", Direction: domain.TracerouteTowards, SNR: 6}},
+	}))
+
+	server := NewUnifiedServer(UnifiedServerConfig{Addr: ":0"}, collector, nil)
+	req := httptest.NewRequest(http.MethodGet, domain.DefaultTraceroutesPath, nil)
+	rec := httptest.NewRecorder()
+
+	server.traceroutesHandler(collector)(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var traceroutes []domain.Traceroute
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &traceroutes))
+	require.Len(t, traceroutes, 1)
+	assert.Equal(t, []string{"1", "2", "3"}, traceroutes[0].Route)
+	assert.Equal(t, 6.0, traceroutes[0].Hops[0].SNR)
+}
+
 func TestUnifiedServer_AlertWebhookHandler(t *testing.T) {
 	t.Parallel()
 	mockAlerter := &mocks.MockAlertSender{}
@@ -0,0 +1,196 @@
+package meshpb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+var (
+	ErrNoChannelKey      = errors.New("no key for channel")
+	ErrDecryptionFailed  = errors.New("decryption failed")
+	ErrEncryptionSkipped = errors.New("channel has encryption disabled")
+)
+
+// defaultKey общеизвестный ключ канала по умолчанию, в него разворачивается PSK "AQ==".
+var defaultKey = []byte{
+	0xd4, 0xf1, 0xbb, 0x3a, 0x20, 0x29, 0x07, 0x59,
+	0xf0, 0xbc, 0xff, 0xab, 0xcf, 0x4e, 0x69, 0x01,
+}
+
+const (
+	aes128KeyLength = 16
+	aes256KeyLength = 32
+	nonceLength     = 16
+)
+
+// ParsePSK декодирует base64 PSK канала и разворачивает однобайтовую запись
+// (1 — ключ по умолчанию, 2..10 — ключ по умолчанию с увеличенным последним байтом).
+func ParsePSK(encoded string) ([]byte, error) {
+	psk, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+
+	switch len(psk) {
+	case 0:
+		return nil, nil
+	case 1:
+		if psk[0] == 0 {
+			return nil, nil
+		}
+		key := append([]byte(nil), defaultKey...)
+		key[len(key)-1] += psk[0] - 1
+		return key, nil
+	case aes128KeyLength, aes256KeyLength:
+		return psk, nil
+	default:
+		return nil, fmt.Errorf("invalid key length %d, expected 1, 16 or 32 bytes", len(psk))
+	}
+}
+
+// ChannelHash хеш, который прошивка пишет в MeshPacket.channel зашифрованных пакетов.
+func ChannelHash(name string, key []byte) uint32 {
+	return uint32(xorHash([]byte(name)) ^ xorHash(key))
+}
+
+func xorHash(b []byte) byte {
+	var h byte
+	for _, v := range b {
+		h ^= v
+	}
+	return h
+}
+
+// Keyring имя канала -> развёрнутый PSK.
+type Keyring struct {
+	keys  map[string][]byte
+	names []string
+}
+
+// NewKeyring разбирает base64 PSK по именам каналов. Некорректные ключи пропускаются
+// и перечисляются в ошибке, корректными можно пользоваться.
+func NewKeyring(channelKeys map[string]string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte, len(channelKeys))}
+	var errs []error
+
+	for name, encoded := range channelKeys {
+		key, err := ParsePSK(encoded)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: %w", name, err))
+			continue
+		}
+		k.keys[name] = key
+		k.names = append(k.names, name)
+	}
+	sort.Strings(k.names)
+
+	return k, errors.Join(errs...)
+}
+
+// Len число настроенных каналов.
+func (k *Keyring) Len() int {
+	if k == nil {
+		return 0
+	}
+	return len(k.keys)
+}
+
+// Decrypt расшифровывает MeshPacket.encrypted. Пробуются только ключи, хеш канала
+// которых совпадает с MeshPacket.channel, первым — ключ канала channelID.
+func (k *Keyring) Decrypt(channelID string, packet *MeshPacket) (*Data, error) {
+	names := k.candidates(channelID, packet.Channel)
+	if len(names) == 0 {
+		return nil, ErrNoChannelKey
+	}
+
+	err := ErrEncryptionSkipped
+	for _, name := range names {
+		key := k.keys[name]
+		if key == nil {
+			continue
+		}
+		data, decryptErr := DecryptPacket(packet, key)
+		if decryptErr == nil {
+			return data, nil
+		}
+		err = decryptErr
+	}
+	return nil, err
+}
+
+// candidates каналы с совпадающим хешем: хеш — один байт, у нескольких каналов
+// он может совпасть.
+func (k *Keyring) candidates(channelID string, hash uint32) []string {
+	if k == nil {
+		return nil
+	}
+
+	var names []string
+	if key, ok := k.keys[channelID]; ok && ChannelHash(channelID, key) == hash {
+		names = append(names, channelID)
+	}
+	for _, name := range k.names {
+		if name != channelID && ChannelHash(name, k.keys[name]) == hash {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// DecryptPacket AES-CTR с nonce = id пакета (uint64 LE) + отправитель (uint32 LE).
+// Чужой ключ даёт мусор, поэтому в результате должны быть известный portnum
+// и payload, который разбирается без ошибок.
+func DecryptPacket(packet *MeshPacket, key []byte) (*Data, error) {
+	if len(packet.Encrypted) == 0 {
+		return nil, ErrDecryptionFailed
+	}
+
+	plain, err := XORKeyStream(key, packet.ID, packet.From, packet.Encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := UnmarshalData(plain)
+	if err != nil || !validData(data) {
+		return nil, ErrDecryptionFailed
+	}
+	return data, nil
+}
+
+// validData проверяет payload портов, которые экспортер умеет разбирать, payload
+// остальных известных портов не проверяется.
+func validData(data *Data) bool {
+	if !KnownPortNum(data.PortNum) {
+		return false
+	}
+	if textPorts[data.PortNum] {
+		return utf8.Valid(data.Payload)
+	}
+	if _, ok := portSchemas[data.PortNum]; !ok {
+		return true
+	}
+	_, err := DecodePayload(data.PortNum, data.Payload)
+	return err == nil
+}
+
+// XORKeyStream шифрует или расшифровывает payload пакета (AES-CTR симметричен).
+func XORKeyStream(key []byte, packetID, from uint32, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceLength)
+	binary.LittleEndian.PutUint64(nonce[0:8], uint64(packetID))
+	binary.LittleEndian.PutUint32(nonce[8:12], from)
+
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, nonce).XORKeyStream(out, in)
+	return out, nil
+}
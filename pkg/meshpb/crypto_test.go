@@ -0,0 +1,176 @@
+package meshpb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePSK(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		psk       string
+		expectLen int
+		expectErr bool
+	}{
+		{name: "default", psk: "AQ==", expectLen: 16},
+		{name: "disabled", psk: "AA==", expectLen: 0},
+		{name: "empty", psk: "", expectLen: 0},
+		{name: "aes128", psk: "1PG7OiApB1nwvP+rz05pAQ==", expectLen: 16},
+		{name: "aes256", psk: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=", expectLen: 32},
+		{name: "invalid_base64", psk: "not base64!", expectErr: true},
+		{name: "invalid_length", psk: "AAEC", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			key, err := ParsePSK(tt.psk)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, key, tt.expectLen)
+		})
+	}
+}
+
+func TestParsePSK_SimpleKeyIndex(t *testing.T) {
+	t.Parallel()
+	key, err := ParsePSK("Ag==")
+
+	require.NoError(t, err)
+	assert.Equal(t, defaultKey[:15], key[:15])
+	assert.Equal(t, defaultKey[15]+1, key[15])
+}
+
+func TestChannelHash_DefaultChannel(t *testing.T) {
+	t.Parallel()
+	key, err := ParsePSK("AQ==")
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(8), ChannelHash("LongFast", key))
+}
+
+func TestKeyring_Decrypt(t *testing.T) {
+	t.Parallel()
+	keyring, err := NewKeyring(map[string]string{"LongFast": "AQ==", "Broken": "###"})
+	require.Error(t, err)
+	assert.Equal(t, 1, keyring.Len())
+
+	key, err := ParsePSK("AQ==")
+	require.NoError(t, err)
+	plain := (&Data{PortNum: PortNumTextMessage, Payload: []byte("hi")}).Marshal()
+	encrypted, err := XORKeyStream(key, 77, 0xf992bd54, plain)
+	require.NoError(t, err)
+
+	packet := &MeshPacket{From: 0xf992bd54, ID: 77, Channel: 8, Encrypted: encrypted}
+
+	data, err := keyring.Decrypt("LongFast", packet)
+	require.NoError(t, err)
+	assert.Equal(t, PortNumTextMessage, data.PortNum)
+	assert.Equal(t, []byte("hi"), data.Payload)
+
+	// без имени канала ключ ищется по hash
+	data, err = keyring.Decrypt("", packet)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hi"), data.Payload)
+
+	_, err = keyring.Decrypt("Unknown", &MeshPacket{From: 1, ID: 1, Channel: 99, Encrypted: encrypted})
+	require.ErrorIs(t, err, ErrNoChannelKey)
+}
+
+func TestKeyring_WrongKey(t *testing.T) {
+	t.Parallel()
+	keyring, err := NewKeyring(map[string]string{"LongFast": "Ag=="})
+	require.NoError(t, err)
+
+	key, err := ParsePSK("AQ==")
+	require.NoError(t, err)
+	plain := (&Data{PortNum: PortNumTextMessage, Payload: []byte("hi")}).Marshal()
+	encrypted, err := XORKeyStream(key, 77, 1, plain)
+	require.NoError(t, err)
+
+	wrongKey, err := ParsePSK("Ag==")
+	require.NoError(t, err)
+	packet := &MeshPacket{From: 1, ID: 77, Channel: ChannelHash("LongFast", wrongKey), Encrypted: encrypted}
+
+	_, err = keyring.Decrypt("LongFast", packet)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestKeyring_ChannelHashMismatch(t *testing.T) {
+	t.Parallel()
+	keyring, err := NewKeyring(map[string]string{"LongFast": "AQ=="})
+	require.NoError(t, err)
+
+	key, err := ParsePSK("AQ==")
+	require.NoError(t, err)
+	plain := (&Data{PortNum: PortNumTextMessage, Payload: []byte("hi")}).Marshal()
+	encrypted, err := XORKeyStream(key, 77, 1, plain)
+	require.NoError(t, err)
+
+	// ключ канала из envelope не пробуется, если hash пакета от другого канала
+	_, err = keyring.Decrypt("LongFast", &MeshPacket{From: 1, ID: 77, Channel: 9, Encrypted: encrypted})
+	require.ErrorIs(t, err, ErrNoChannelKey)
+}
+
+func TestKeyring_SharedChannelHash(t *testing.T) {
+	t.Parallel()
+	// "LongFast" с ключом "AQ==" и "LongFasw" с ключом "Ag==" дают один hash,
+	// пробуются оба ключа
+	keyring, err := NewKeyring(map[string]string{"LongFast": "AQ==", "LongFasw": "Ag=="})
+	require.NoError(t, err)
+
+	key, err := ParsePSK("Ag==")
+	require.NoError(t, err)
+	require.Equal(t, ChannelHash("LongFast", defaultKey), ChannelHash("LongFasw", key))
+
+	plain := (&Data{PortNum: PortNumTextMessage, Payload: []byte("hi")}).Marshal()
+	encrypted, err := XORKeyStream(key, 77, 1, plain)
+	require.NoError(t, err)
+	packet := &MeshPacket{From: 1, ID: 77, Channel: ChannelHash("LongFasw", key), Encrypted: encrypted}
+
+	data, err := keyring.Decrypt("LongFast", packet)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hi"), data.Payload)
+}
+
+func TestDecryptPacket_Validation(t *testing.T) {
+	t.Parallel()
+	key, err := ParsePSK("AQ==")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		data      Data
+		expectErr bool
+	}{
+		{name: "text", data: Data{PortNum: PortNumTextMessage, Payload: []byte("hi")}},
+		{name: "telemetry", data: Data{PortNum: PortNumTelemetry, Payload: []byte{0x12, 0x02, 0x08, 0x55}}},
+		{name: "private_opaque", data: Data{PortNum: PortNumPrivate, Payload: []byte{0xff, 0x00}}},
+		{name: "unknown_port", data: Data{PortNum: 100, Payload: []byte("hi")}, expectErr: true},
+		{name: "above_max_port", data: Data{PortNum: PortNumMaxPortNumberApp + 1}, expectErr: true},
+		{name: "invalid_utf8", data: Data{PortNum: PortNumTextMessage, Payload: []byte{0xff, 0xfe}}, expectErr: true},
+		{name: "wrong_wire_type", data: Data{PortNum: PortNumTelemetry, Payload: []byte{0x08, 0x01}}, expectErr: true},
+		{name: "broken_payload", data: Data{PortNum: PortNumTelemetry, Payload: []byte{0x12, 0x05, 0x08}}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			encrypted, err := XORKeyStream(key, 77, 1, tt.data.Marshal())
+			require.NoError(t, err)
+
+			_, err = DecryptPacket(&MeshPacket{From: 1, ID: 77, Encrypted: encrypted}, key)
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrDecryptionFailed)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
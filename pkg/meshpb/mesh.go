@@ -0,0 +1,246 @@
+package meshpb
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// PortNum приложение расшифрованного пакета (portnums.proto).
+type PortNum uint32
+
+const (
+	PortNumUnknown          PortNum = 0
+	PortNumTextMessage      PortNum = 1
+	PortNumRemoteHardware   PortNum = 2
+	PortNumPosition         PortNum = 3
+	PortNumNodeInfo         PortNum = 4
+	PortNumRouting          PortNum = 5
+	PortNumAdmin            PortNum = 6
+	PortNumWaypoint         PortNum = 8
+	PortNumDetectionSensor  PortNum = 10
+	PortNumPaxcounter       PortNum = 34
+	PortNumRangeTest        PortNum = 66
+	PortNumTelemetry        PortNum = 67
+	PortNumTraceroute       PortNum = 70
+	PortNumNeighborInfo     PortNum = 71
+	PortNumMapReport        PortNum = 73
+	PortNumPrivate          PortNum = 256
+	PortNumAtakForwarder    PortNum = 257
+	PortNumMaxPortNumberApp PortNum = 511
+)
+
+// firmwarePorts portnum из portnums.proto меньше PRIVATE_APP.
+var firmwarePorts = map[PortNum]bool{
+	PortNumTextMessage: true, PortNumRemoteHardware: true, PortNumPosition: true,
+	PortNumNodeInfo: true, PortNumRouting: true, PortNumAdmin: true,
+	7: true, PortNumWaypoint: true, 9: true, PortNumDetectionSensor: true, 11: true, 12: true,
+	32: true, 33: true, PortNumPaxcounter: true,
+	64: true, 65: true, PortNumRangeTest: true, PortNumTelemetry: true, 68: true, 69: true,
+	PortNumTraceroute: true, PortNumNeighborInfo: true, 72: true, PortNumMapReport: true,
+	74: true, 76: true, 77: true,
+}
+
+// KnownPortNum port — приложение прошивки или из диапазона PRIVATE_APP..MAX
+// для пользовательских приложений.
+func KnownPortNum(port PortNum) bool {
+	return firmwarePorts[port] || (port >= PortNumPrivate && port <= PortNumMaxPortNumberApp)
+}
+
+// ServiceEnvelope обёртка, которую шлюзы публикуют в MQTT (mqtt.proto).
+type ServiceEnvelope struct {
+	Packet    *MeshPacket
+	ChannelID string
+	GatewayID string
+}
+
+// MeshPacket радиопакет, как его принял шлюз (mesh.proto).
+type MeshPacket struct {
+	From      uint32
+	To        uint32
+	Channel   uint32
+	Decoded   *Data
+	Encrypted []byte
+	ID        uint32
+	RxTime    uint32
+	RxSNR     float64
+	HopLimit  uint32
+	WantAck   bool
+	RxRSSI    int32
+	ViaMQTT   bool
+	HopStart  uint32
+	RelayNode uint32
+}
+
+// Data расшифрованный payload приложения из MeshPacket.
+type Data struct {
+	PortNum      PortNum
+	Payload      []byte
+	WantResponse bool
+	Dest         uint32
+	Source       uint32
+	RequestID    uint32
+	ReplyID      uint32
+}
+
+var meshPacketFields = map[protowire.Number]func(p *MeshPacket, f field) error{
+	1: func(p *MeshPacket, f field) error { p.From = f.uint32(); return nil },
+	2: func(p *MeshPacket, f field) error { p.To = f.uint32(); return nil },
+	3: func(p *MeshPacket, f field) error { p.Channel = f.uint32(); return nil },
+	4: func(p *MeshPacket, f field) error {
+		data, err := UnmarshalData(f.bytes)
+		p.Decoded = data
+		return err
+	},
+	5:  func(p *MeshPacket, f field) error { p.Encrypted = f.bytes; return nil },
+	6:  func(p *MeshPacket, f field) error { p.ID = f.uint32(); return nil },
+	7:  func(p *MeshPacket, f field) error { p.RxTime = f.uint32(); return nil },
+	8:  func(p *MeshPacket, f field) error { p.RxSNR = f.float32(); return nil },
+	9:  func(p *MeshPacket, f field) error { p.HopLimit = f.uint32(); return nil },
+	10: func(p *MeshPacket, f field) error { p.WantAck = f.bool(); return nil },
+	12: func(p *MeshPacket, f field) error { p.RxRSSI = f.int32(); return nil },
+	14: func(p *MeshPacket, f field) error { p.ViaMQTT = f.bool(); return nil },
+	15: func(p *MeshPacket, f field) error { p.HopStart = f.uint32(); return nil },
+	19: func(p *MeshPacket, f field) error { p.RelayNode = f.uint32(); return nil },
+}
+
+var dataFields = map[protowire.Number]func(d *Data, f field){
+	1: func(d *Data, f field) { d.PortNum = PortNum(f.uint32()) },
+	2: func(d *Data, f field) { d.Payload = f.bytes },
+	3: func(d *Data, f field) { d.WantResponse = f.bool() },
+	4: func(d *Data, f field) { d.Dest = f.uint32() },
+	5: func(d *Data, f field) { d.Source = f.uint32() },
+	6: func(d *Data, f field) { d.RequestID = f.uint32() },
+	7: func(d *Data, f field) { d.ReplyID = f.uint32() },
+}
+
+// UnmarshalServiceEnvelope разбирает ServiceEnvelope из MQTT сообщения.
+func UnmarshalServiceEnvelope(b []byte) (*ServiceEnvelope, error) {
+	env := &ServiceEnvelope{}
+	err := forEachField(b, func(f field) error {
+		switch f.num {
+		case 1:
+			packet, err := UnmarshalMeshPacket(f.bytes)
+			if err != nil {
+				return fmt.Errorf("packet: %w", err)
+			}
+			env.Packet = packet
+		case 2:
+			env.ChannelID = f.string()
+		case 3:
+			env.GatewayID = f.string()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// UnmarshalMeshPacket разбирает MeshPacket.
+func UnmarshalMeshPacket(b []byte) (*MeshPacket, error) {
+	packet := &MeshPacket{}
+	err := forEachField(b, func(f field) error {
+		if set, ok := meshPacketFields[f.num]; ok {
+			return set(packet, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// UnmarshalData разбирает Data из MeshPacket.
+func UnmarshalData(b []byte) (*Data, error) {
+	data := &Data{}
+	err := forEachField(b, func(f field) error {
+		if set, ok := dataFields[f.num]; ok {
+			set(data, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Marshal кодирует envelope, нужен для downlink и тестов.
+func (e *ServiceEnvelope) Marshal() []byte {
+	var b []byte
+	if e.Packet != nil {
+		b = appendBytes(b, 1, e.Packet.Marshal())
+	}
+	b = appendString(b, 2, e.ChannelID)
+	b = appendString(b, 3, e.GatewayID)
+	return b
+}
+
+// Marshal кодирует пакет.
+func (p *MeshPacket) Marshal() []byte {
+	var b []byte
+	b = appendFixed32(b, 1, p.From)
+	b = appendFixed32(b, 2, p.To)
+	b = appendVarint(b, 3, uint64(p.Channel))
+	if p.Decoded != nil {
+		b = appendBytes(b, 4, p.Decoded.Marshal())
+	}
+	b = appendBytes(b, 5, p.Encrypted)
+	b = appendFixed32(b, 6, p.ID)
+	b = appendFixed32(b, 7, p.RxTime)
+	b = appendFixed32(b, 8, math.Float32bits(float32(p.RxSNR)))
+	b = appendVarint(b, 9, uint64(p.HopLimit))
+	b = appendVarint(b, 10, protowire.EncodeBool(p.WantAck))
+	b = appendVarint(b, 12, uint64(int64(p.RxRSSI)))
+	b = appendVarint(b, 14, protowire.EncodeBool(p.ViaMQTT))
+	b = appendVarint(b, 15, uint64(p.HopStart))
+	b = appendVarint(b, 19, uint64(p.RelayNode))
+	return b
+}
+
+// Marshal кодирует Data.
+func (d *Data) Marshal() []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(d.PortNum))
+	b = appendBytes(b, 2, d.Payload)
+	b = appendVarint(b, 3, protowire.EncodeBool(d.WantResponse))
+	b = appendFixed32(b, 4, d.Dest)
+	b = appendFixed32(b, 5, d.Source)
+	b = appendFixed32(b, 6, d.RequestID)
+	b = appendFixed32(b, 7, d.ReplyID)
+	return b
+}
+
+// как в proto3, нулевые значения не пишутся.
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed32(b []byte, num protowire.Number, v uint32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	return appendBytes(b, num, []byte(v))
+}
@@ -0,0 +1,265 @@
+package meshpb
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendFloat(b []byte, num protowire.Number, v float32) []byte {
+	return appendFixed32(b, num, math.Float32bits(v))
+}
+
+func TestServiceEnvelope_RoundTrip(t *testing.T) {
+	t.Parallel()
+	envelope := &ServiceEnvelope{
+		Packet: &MeshPacket{
+			From:     0xf992bd54,
+			To:       math.MaxUint32,
+			ID:       42,
+			RxTime:   1700000000,
+			RxSNR:    6.25,
+			RxRSSI:   -97,
+			HopLimit: 2,
+			HopStart: 3,
+			Decoded: &Data{
+				PortNum: PortNumTextMessage,
+				Payload: []byte("hello"),
+			},
+		},
+		ChannelID: "LongFast",
+		GatewayID: "!f992bd54",
+	}
+
+	decoded, err := UnmarshalServiceEnvelope(envelope.Marshal())
+
+	require.NoError(t, err)
+	assert.Equal(t, envelope, decoded)
+}
+
+func TestUnmarshalServiceEnvelope_Invalid(t *testing.T) {
+	t.Parallel()
+	_, err := UnmarshalServiceEnvelope([]byte{0x0a, 0x10, 0x01})
+	require.Error(t, err)
+}
+
+func TestUnmarshalMeshPacket_SkipsUnknownFields(t *testing.T) {
+	t.Parallel()
+	b := appendFixed32(nil, 1, 123)
+	b = appendVarint(b, 99, 7)
+	b = appendBytes(b, 100, []byte("future"))
+
+	packet, err := UnmarshalMeshPacket(b)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint32(123), packet.From)
+}
+
+func TestDecodePayload_Telemetry(t *testing.T) {
+	t.Parallel()
+	device := appendVarint(nil, 1, 85)
+	device = appendFloat(device, 2, 4.1)
+	device = appendVarint(device, 5, 3600)
+	environment := appendFloat(nil, 1, 23.4)
+	environment = appendFloat(environment, 2, 65.2)
+
+	telemetry := appendFixed32(nil, 1, 1700000000)
+	telemetry = appendBytes(telemetry, 2, device)
+	telemetry = appendBytes(telemetry, 3, environment)
+
+	payload, err := DecodePayload(PortNumTelemetry, telemetry)
+
+	require.NoError(t, err)
+	assert.Equal(t, 85.0, payload["battery_level"])
+	assert.Equal(t, 4.1, payload["voltage"])
+	assert.Equal(t, 3600.0, payload["uptime_seconds"])
+	assert.Equal(t, 23.4, payload["temperature"])
+	assert.Equal(t, 65.2, payload["relative_humidity"])
+	assert.Equal(t, 1700000000.0, payload["time"])
+}
+
+func TestDecodePayload_WeatherAndAirQuality(t *testing.T) {
+	t.Parallel()
+	environment := appendFloat(nil, 9, 1250.5)
+	environment = appendVarint(environment, 13, 270)
+	environment = appendFloat(environment, 14, 3.5)
+	environment = appendVarint(environment, 21, 42)
+	airQuality := appendVarint(nil, 2, 12)
+	airQuality = appendVarint(airQuality, 13, 640)
+
+	telemetry := appendBytes(nil, 3, environment)
+	telemetry = appendBytes(telemetry, 4, airQuality)
+
+	payload, err := DecodePayload(PortNumTelemetry, telemetry)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1250.5, payload["lux"])
+	assert.Equal(t, 270.0, payload["wind_direction"])
+	assert.Equal(t, 3.5, payload["wind_speed"])
+	assert.Equal(t, 42.0, payload["soil_moisture"])
+	assert.Equal(t, 12.0, payload["pm25"])
+	assert.Equal(t, 640.0, payload["co2"])
+}
+
+func TestDecodePayload_LocalStats(t *testing.T) {
+	t.Parallel()
+	stats := appendVarint(nil, 1, 7200)
+	stats = appendFloat(stats, 2, 12.5)
+	stats = appendVarint(stats, 4, 300)
+	stats = appendVarint(stats, 6, 4)
+	stats = appendVarint(stats, 13, 81920)
+
+	payload, err := DecodePayload(PortNumTelemetry, appendBytes(nil, 6, stats))
+
+	require.NoError(t, err)
+	assert.Equal(t, 7200.0, payload["uptime_seconds"])
+	assert.Equal(t, 12.5, payload["channel_utilization"])
+	assert.Equal(t, 300.0, payload["num_packets_tx"])
+	assert.Equal(t, 4.0, payload["num_packets_rx_bad"])
+	assert.Equal(t, 81920.0, payload["heap_free_bytes"])
+}
+
+func TestDecodePayload_NodeInfo(t *testing.T) {
+	t.Parallel()
+	user := appendString(nil, 1, "!f992bd54")
+	user = appendString(user, 2, "Test Node")
+	user = appendString(user, 3, "TN01")
+	user = appendVarint(user, 5, 43)
+	user = appendVarint(user, 7, 2)
+
+	payload, err := DecodePayload(PortNumNodeInfo, user)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Test Node", payload["longname"])
+	assert.Equal(t, "TN01", payload["shortname"])
+	assert.Equal(t, 43.0, payload["hardware"])
+	assert.Equal(t, 2.0, payload["role"])
+}
+
+func TestDecodePayload_Position(t *testing.T) {
+	t.Parallel()
+	latitude, altitude := int32(-337000000), int64(-12)
+	position := appendFixed32(nil, 1, uint32(latitude))
+	position = appendFixed32(position, 2, 1512000000)
+	position = appendVarint(position, 3, uint64(altitude))
+	position = appendVarint(position, 19, 9)
+
+	payload, err := DecodePayload(PortNumPosition, position)
+
+	require.NoError(t, err)
+	assert.Equal(t, -337000000.0, payload["latitude_i"])
+	assert.Equal(t, 1512000000.0, payload["longitude_i"])
+	assert.Equal(t, -12.0, payload["altitude"])
+	assert.Equal(t, 9.0, payload["sats_in_view"])
+}
+
+func TestDecodePayload_MapReport(t *testing.T) {
+	t.Parallel()
+	report := appendBytes(nil, 1, []byte("Base"))
+	report = appendBytes(report, 5, []byte("2.5.15.79da236"))
+	report = appendVarint(report, 6, 9)
+	report = appendVarint(report, 7, 4)
+	report = appendVarint(report, 8, 1)
+	report = appendFixed32(report, 9, 557500000)
+	report = appendFixed32(report, 10, 376200000)
+	report = appendVarint(report, 12, 13)
+	report = appendVarint(report, 13, 17)
+
+	payload, err := DecodePayload(PortNumMapReport, report)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Base", payload["long_name"])
+	assert.Equal(t, "2.5.15.79da236", payload["firmware_version"])
+	assert.Equal(t, 9.0, payload["region"])
+	assert.Equal(t, 4.0, payload["modem_preset"])
+	assert.Equal(t, true, payload["has_default_channel"])
+	assert.Equal(t, 557500000.0, payload["latitude_i"])
+	assert.Equal(t, 13.0, payload["position_precision"])
+	assert.Equal(t, 17.0, payload["num_online_local_nodes"])
+}
+
+func TestDecodePayload_Traceroute(t *testing.T) {
+	t.Parallel()
+	var route, snrTowards []byte
+	route = protowire.AppendFixed32(route, 111)
+	route = protowire.AppendFixed32(route, 222)
+	unknown := int64(-128)
+	for _, snr := range []int64{24, -10, unknown} {
+		snrTowards = protowire.AppendVarint(snrTowards, uint64(snr))
+	}
+
+	discovery := appendBytes(nil, 1, route)
+	discovery = appendBytes(discovery, 2, snrTowards)
+	// неупакованное кодирование тоже допустимо
+	discovery = appendFixed32(discovery, 3, 333)
+
+	payload, err := DecodePayload(PortNumTraceroute, discovery)
+
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{111.0, 222.0}, payload["route"])
+	assert.Equal(t, []interface{}{24.0, -10.0, -128.0}, payload["snr_towards"])
+	assert.Equal(t, []interface{}{333.0}, payload["route_back"])
+	assert.NotContains(t, payload, "snr_back")
+}
+
+func TestDecodePayload_NeighborInfo(t *testing.T) {
+	t.Parallel()
+	first := appendVarint(nil, 1, 111)
+	first = appendFloat(first, 2, 5.5)
+	second := appendVarint(nil, 1, 222)
+	second = appendFloat(second, 2, -3.25)
+
+	info := appendVarint(nil, 1, 100)
+	info = appendVarint(info, 3, 900)
+	info = appendBytes(info, 4, first)
+	info = appendBytes(info, 4, second)
+
+	payload, err := DecodePayload(PortNumNeighborInfo, info)
+
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, payload["node_id"])
+	assert.Equal(t, 900.0, payload["node_broadcast_interval_secs"])
+	neighbors, ok := payload["neighbors"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, neighbors, 2)
+	assert.Equal(t, map[string]interface{}{"node_id": 222.0, "snr": -3.25}, neighbors[1])
+}
+
+func TestDecodePayload_Text(t *testing.T) {
+	t.Parallel()
+	payload, err := DecodePayload(PortNumTextMessage, []byte("hi"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "hi", payload["text"])
+}
+
+func TestDecodePayload_TextPorts(t *testing.T) {
+	t.Parallel()
+	for _, port := range []PortNum{PortNumDetectionSensor, PortNumRangeTest} {
+		payload, err := DecodePayload(port, []byte("seq 7"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "seq 7", payload["text"])
+	}
+}
+
+func TestDecodePayload_Paxcount(t *testing.T) {
+	t.Parallel()
+	pax := appendVarint(nil, 1, 12)
+	pax = appendVarint(pax, 2, 30)
+	pax = appendVarint(pax, 3, 3600)
+
+	payload, err := DecodePayload(PortNumPaxcounter, pax)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"wifi_count": 12.0, "ble_count": 30.0, "uptime": 3600.0}, payload)
+}
+
+func TestDecodePayload_Unsupported(t *testing.T) {
+	t.Parallel()
+	_, err := DecodePayload(PortNumAdmin, []byte{0x08, 0x01})
+	require.Error(t, err)
+}
@@ -0,0 +1,347 @@
+package meshpb
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type kind int
+
+const (
+	kindUint32 kind = iota
+	kindInt32
+	kindSint32
+	kindFixed32
+	kindSfixed32
+	kindFloat
+	kindBool
+	kindString
+	kindMessage
+)
+
+// fieldSpec поле protobuf и ключ, под которым его пишет JSON прошивки: разобранный
+// payload выглядит так же, как в топиках msh/.../json/.
+type fieldSpec struct {
+	name     string
+	kind     kind
+	schema   schema
+	repeated bool
+	flatten  bool // поля вложенного сообщения пишутся в родительскую map
+}
+
+type schema map[protowire.Number]fieldSpec
+
+var deviceMetricsSchema = schema{
+	1: {name: "battery_level", kind: kindUint32},
+	2: {name: "voltage", kind: kindFloat},
+	3: {name: "channel_utilization", kind: kindFloat},
+	4: {name: "air_util_tx", kind: kindFloat},
+	5: {name: "uptime_seconds", kind: kindUint32},
+}
+
+var environmentMetricsSchema = schema{
+	1:  {name: "temperature", kind: kindFloat},
+	2:  {name: "relative_humidity", kind: kindFloat},
+	3:  {name: "barometric_pressure", kind: kindFloat},
+	4:  {name: "gas_resistance", kind: kindFloat},
+	5:  {name: "voltage", kind: kindFloat},
+	6:  {name: "current", kind: kindFloat},
+	7:  {name: "iaq", kind: kindUint32},
+	8:  {name: "distance", kind: kindFloat},
+	9:  {name: "lux", kind: kindFloat},
+	10: {name: "white_lux", kind: kindFloat},
+	11: {name: "ir_lux", kind: kindFloat},
+	12: {name: "uv_lux", kind: kindFloat},
+	13: {name: "wind_direction", kind: kindUint32},
+	14: {name: "wind_speed", kind: kindFloat},
+	15: {name: "weight", kind: kindFloat},
+	16: {name: "wind_gust", kind: kindFloat},
+	17: {name: "wind_lull", kind: kindFloat},
+	18: {name: "radiation", kind: kindFloat},
+	19: {name: "rainfall_1h", kind: kindFloat},
+	20: {name: "rainfall_24h", kind: kindFloat},
+	21: {name: "soil_moisture", kind: kindUint32},
+	22: {name: "soil_temperature", kind: kindFloat},
+}
+
+// airQualityMetricsSchema имена полей как в JSON прошивки (pm10 = pm10_standard).
+var airQualityMetricsSchema = schema{
+	1:  {name: "pm10", kind: kindUint32},
+	2:  {name: "pm25", kind: kindUint32},
+	3:  {name: "pm100", kind: kindUint32},
+	4:  {name: "pm10_e", kind: kindUint32},
+	5:  {name: "pm25_e", kind: kindUint32},
+	6:  {name: "pm100_e", kind: kindUint32},
+	7:  {name: "particles_03um", kind: kindUint32},
+	8:  {name: "particles_05um", kind: kindUint32},
+	9:  {name: "particles_10um", kind: kindUint32},
+	10: {name: "particles_25um", kind: kindUint32},
+	11: {name: "particles_50um", kind: kindUint32},
+	12: {name: "particles_100um", kind: kindUint32},
+	13: {name: "co2", kind: kindUint32},
+}
+
+var powerMetricsSchema = schema{
+	1: {name: "ch1_voltage", kind: kindFloat},
+	2: {name: "ch1_current", kind: kindFloat},
+	3: {name: "ch2_voltage", kind: kindFloat},
+	4: {name: "ch2_current", kind: kindFloat},
+	5: {name: "ch3_voltage", kind: kindFloat},
+	6: {name: "ch3_current", kind: kindFloat},
+}
+
+var localStatsSchema = schema{
+	1:  {name: "uptime_seconds", kind: kindUint32},
+	2:  {name: "channel_utilization", kind: kindFloat},
+	3:  {name: "air_util_tx", kind: kindFloat},
+	4:  {name: "num_packets_tx", kind: kindUint32},
+	5:  {name: "num_packets_rx", kind: kindUint32},
+	6:  {name: "num_packets_rx_bad", kind: kindUint32},
+	7:  {name: "num_online_nodes", kind: kindUint32},
+	8:  {name: "num_total_nodes", kind: kindUint32},
+	9:  {name: "num_rx_dupe", kind: kindUint32},
+	10: {name: "num_tx_relay", kind: kindUint32},
+	11: {name: "num_tx_relay_canceled", kind: kindUint32},
+	12: {name: "heap_total_bytes", kind: kindUint32},
+	13: {name: "heap_free_bytes", kind: kindUint32},
+}
+
+var telemetrySchema = schema{
+	1: {name: "time", kind: kindFixed32},
+	2: {kind: kindMessage, schema: deviceMetricsSchema, flatten: true},
+	3: {kind: kindMessage, schema: environmentMetricsSchema, flatten: true},
+	4: {kind: kindMessage, schema: airQualityMetricsSchema, flatten: true},
+	5: {kind: kindMessage, schema: powerMetricsSchema, flatten: true},
+	6: {kind: kindMessage, schema: localStatsSchema, flatten: true},
+}
+
+var userSchema = schema{
+	1: {name: "id", kind: kindString},
+	2: {name: "longname", kind: kindString},
+	3: {name: "shortname", kind: kindString},
+	5: {name: "hardware", kind: kindUint32},
+	7: {name: "role", kind: kindUint32},
+}
+
+var positionSchema = schema{
+	1:  {name: "latitude_i", kind: kindSfixed32},
+	2:  {name: "longitude_i", kind: kindSfixed32},
+	3:  {name: "altitude", kind: kindInt32},
+	4:  {name: "time", kind: kindFixed32},
+	9:  {name: "altitude_hae", kind: kindSint32},
+	15: {name: "ground_speed", kind: kindUint32},
+	16: {name: "ground_track", kind: kindUint32},
+	19: {name: "sats_in_view", kind: kindUint32},
+	23: {name: "precision_bits", kind: kindUint32},
+}
+
+var neighborSchema = schema{
+	1: {name: "node_id", kind: kindUint32},
+	2: {name: "snr", kind: kindFloat},
+	3: {name: "last_rx_time", kind: kindFixed32},
+	4: {name: "node_broadcast_interval_secs", kind: kindUint32},
+}
+
+var neighborInfoSchema = schema{
+	1: {name: "node_id", kind: kindUint32},
+	2: {name: "last_sent_by_id", kind: kindUint32},
+	3: {name: "node_broadcast_interval_secs", kind: kindUint32},
+	4: {name: "neighbors", kind: kindMessage, schema: neighborSchema, repeated: true},
+}
+
+var waypointSchema = schema{
+	1: {name: "id", kind: kindUint32},
+	2: {name: "latitude_i", kind: kindSfixed32},
+	3: {name: "longitude_i", kind: kindSfixed32},
+	4: {name: "expire", kind: kindUint32},
+	5: {name: "locked_to", kind: kindUint32},
+	6: {name: "name", kind: kindString},
+	7: {name: "description", kind: kindString},
+	8: {name: "icon", kind: kindFixed32},
+}
+
+// mapReportSchema MapReport из mqtt.proto, enum'ы остаются числами.
+var mapReportSchema = schema{
+	1:  {name: "long_name", kind: kindString},
+	2:  {name: "short_name", kind: kindString},
+	3:  {name: "role", kind: kindUint32},
+	4:  {name: "hw_model", kind: kindUint32},
+	5:  {name: "firmware_version", kind: kindString},
+	6:  {name: "region", kind: kindUint32},
+	7:  {name: "modem_preset", kind: kindUint32},
+	8:  {name: "has_default_channel", kind: kindBool},
+	9:  {name: "latitude_i", kind: kindSfixed32},
+	10: {name: "longitude_i", kind: kindSfixed32},
+	11: {name: "altitude", kind: kindInt32},
+	12: {name: "position_precision", kind: kindUint32},
+	13: {name: "num_online_local_nodes", kind: kindUint32},
+}
+
+// routeDiscoverySchema RouteDiscovery из mesh.proto: узлы маршрута и SNR*4 на каждом хопе.
+var routeDiscoverySchema = schema{
+	1: {name: "route", kind: kindFixed32, repeated: true},
+	2: {name: "snr_towards", kind: kindInt32, repeated: true},
+	3: {name: "route_back", kind: kindFixed32, repeated: true},
+	4: {name: "snr_back", kind: kindInt32, repeated: true},
+}
+
+// routingSchema Routing из mesh.proto, route_request/route_reply не нужны.
+var routingSchema = schema{
+	3: {name: "error_reason", kind: kindUint32},
+}
+
+// paxcountSchema Paxcount из paxcount.proto, имена полей как в JSON прошивки.
+var paxcountSchema = schema{
+	1: {name: "wifi_count", kind: kindUint32},
+	2: {name: "ble_count", kind: kindUint32},
+	3: {name: "uptime", kind: kindUint32},
+}
+
+// textPorts приложения, payload которых — UTF-8 текст.
+var textPorts = map[PortNum]bool{
+	PortNumTextMessage:     true,
+	PortNumDetectionSensor: true,
+	PortNumRangeTest:       true,
+}
+
+var portSchemas = map[PortNum]schema{
+	PortNumTelemetry:    telemetrySchema,
+	PortNumNodeInfo:     userSchema,
+	PortNumPosition:     positionSchema,
+	PortNumNeighborInfo: neighborInfoSchema,
+	PortNumWaypoint:     waypointSchema,
+	PortNumMapReport:    mapReportSchema,
+	PortNumTraceroute:   routeDiscoverySchema,
+	PortNumRouting:      routingSchema,
+	PortNumPaxcounter:   paxcountSchema,
+}
+
+// DecodePayload переводит payload приложения в map, как у JSON прошивки. Числа —
+// float64, как после encoding/json.
+func DecodePayload(port PortNum, payload []byte) (map[string]interface{}, error) {
+	if textPorts[port] {
+		return map[string]interface{}{"text": string(payload)}, nil
+	}
+
+	s, ok := portSchemas[port]
+	if !ok {
+		return nil, fmt.Errorf("unsupported portnum %d", port)
+	}
+	return decodeMessage(payload, s)
+}
+
+func decodeMessage(b []byte, s schema) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	err := forEachField(b, func(f field) error {
+		spec, ok := s[f.num]
+		if !ok {
+			return nil
+		}
+		if !spec.accepts(f.typ) {
+			return fmt.Errorf("%s: unexpected wire type %d", spec.name, f.typ)
+		}
+		return spec.decodeInto(result, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// accepts подходит ли wire type typ полю, повторяющиеся скаляры могут быть
+// и упакованными.
+func (spec fieldSpec) accepts(typ protowire.Type) bool {
+	switch spec.kind {
+	case kindString, kindMessage:
+		return typ == protowire.BytesType
+	case kindFixed32, kindSfixed32, kindFloat:
+		return typ == protowire.Fixed32Type || (spec.repeated && typ == protowire.BytesType)
+	default:
+		return typ == protowire.VarintType || (spec.repeated && typ == protowire.BytesType)
+	}
+}
+
+func (spec fieldSpec) decodeInto(result map[string]interface{}, f field) error {
+	if spec.kind != kindMessage {
+		return spec.decodeScalar(result, f)
+	}
+
+	nested, err := decodeMessage(f.bytes, spec.schema)
+	if err != nil {
+		return fmt.Errorf("%s: %w", spec.name, err)
+	}
+
+	switch {
+	case spec.flatten:
+		for k, v := range nested {
+			result[k] = v
+		}
+	case spec.repeated:
+		list, _ := result[spec.name].([]interface{})
+		result[spec.name] = append(list, nested)
+	default:
+		result[spec.name] = nested
+	}
+	return nil
+}
+
+// decodeScalar повторяющиеся скаляры становятся []interface{}, по спецификации
+// принимаются и упакованные, и неупакованные.
+func (spec fieldSpec) decodeScalar(result map[string]interface{}, f field) error {
+	if !spec.repeated {
+		result[spec.name] = spec.scalar(f)
+		return nil
+	}
+
+	list, _ := result[spec.name].([]interface{})
+	if f.typ != protowire.BytesType || spec.kind == kindString {
+		result[spec.name] = append(list, spec.scalar(f))
+		return nil
+	}
+	values, err := spec.unpack(f.bytes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", spec.name, err)
+	}
+	result[spec.name] = append(list, values...)
+	return nil
+}
+
+func (spec fieldSpec) unpack(b []byte) ([]interface{}, error) {
+	var values []interface{}
+	for len(b) > 0 {
+		var f field
+		var n int
+		switch spec.kind {
+		case kindFixed32, kindSfixed32, kindFloat:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.value = uint64(v)
+		default:
+			f.value, n = protowire.ConsumeVarint(b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		values = append(values, spec.scalar(f))
+	}
+	return values, nil
+}
+
+func (spec fieldSpec) scalar(f field) interface{} {
+	switch spec.kind {
+	case kindInt32, kindSfixed32:
+		return float64(f.int32())
+	case kindSint32:
+		return float64(f.sint32())
+	case kindFloat:
+		return f.float32()
+	case kindBool:
+		return f.bool()
+	case kindString:
+		return f.string()
+	default:
+		return float64(f.uint32())
+	}
+}
@@ -0,0 +1,77 @@
+// Package meshpb разбирает protobuf сообщения Meshtastic, которые шлюзы публикуют
+// в топики msh/<region>/2/e/<channel>/<gateway>.
+//
+// Разбираются только поля, нужные экспортеру, неизвестные поля пропускаются,
+// чтобы новые прошивки не ломали разбор.
+package meshpb
+
+import (
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field одна пара ключ/значение wire format protobuf.
+type field struct {
+	num   protowire.Number
+	typ   protowire.Type
+	value uint64 // значения varint, fixed32 и fixed64
+	bytes []byte // значения length-delimited
+}
+
+func forEachField(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.value = uint64(v)
+		case protowire.Fixed64Type:
+			f.value, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f field) uint32() uint32 { return uint32(f.value) }
+
+func (f field) int32() int32 { return int32(f.value) }
+
+func (f field) sint32() int32 { return int32(protowire.DecodeZigZag(f.value & math.MaxUint32)) }
+
+func (f field) bool() bool { return f.value != 0 }
+
+func (f field) string() string { return string(f.bytes) }
+
+// float32 кратчайшее float64 представление float из protobuf: 23.4 остаётся
+// 23.4, а не 23.399999618530273.
+func (f field) float32() float64 {
+	v := math.Float32frombits(uint32(f.value))
+	parsed, err := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	if err != nil {
+		return float64(v)
+	}
+	return parsed
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"time"
 
@@ -22,7 +23,24 @@ type MockMetricsCollector struct {
 	Registry                 *prometheus.Registry
 	TelemetryData            []domain.TelemetryData
 	NodeInfoData             []domain.NodeInfo
-	LastStateFile            string
+	PositionData             []domain.Position
+	NeighborInfoData         []domain.NeighborInfo
+	MapReports               []domain.MapReport
+	Traceroutes              []domain.Traceroute
+	RoutingResults           []domain.RoutingResult
+	DetectionEvents          []domain.DetectionEvent
+	Paxcounts                []domain.Paxcount
+	RangeTestPackets         []domain.RangeTestPacket
+	MappedValues             []domain.MappedValue
+	// SkippedMappings метрики маппингов, которые коллектор будто бы не зарегистрировал
+	SkippedMappings       []string
+	UndecryptableChannels []string
+	PacketHops            map[string]int
+	Receptions            []domain.Reception
+	DuplicateGateways     []string
+	RelayNodes            []string
+	DeliveryStats         []domain.DeliveryStats
+	LastStateFile         string
 }
 
 func (m *MockMetricsCollector) CollectTelemetry(data domain.TelemetryData) error {
@@ -50,6 +68,7 @@ func (m *MockMetricsCollector) CollectTextMessage(msg domain.TextMessage) error
 func (m *MockMetricsCollector) CollectPosition(pos domain.Position) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.PositionData = append(m.PositionData, pos)
 	return nil
 }
 
@@ -62,9 +81,64 @@ func (m *MockMetricsCollector) CollectWaypoint(wp domain.Waypoint) error {
 func (m *MockMetricsCollector) CollectNeighborInfo(ni domain.NeighborInfo) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.NeighborInfoData = append(m.NeighborInfoData, ni)
 	return nil
 }
 
+func (m *MockMetricsCollector) CollectMapReport(report domain.MapReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MapReports = append(m.MapReports, report)
+	return nil
+}
+
+func (m *MockMetricsCollector) CollectTraceroute(tr domain.Traceroute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Traceroutes = append(m.Traceroutes, tr)
+	return nil
+}
+
+func (m *MockMetricsCollector) CollectRoutingResult(result domain.RoutingResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RoutingResults = append(m.RoutingResults, result)
+	return nil
+}
+
+func (m *MockMetricsCollector) CollectDetection(event domain.DetectionEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.DetectionEvents = append(m.DetectionEvents, event)
+	return nil
+}
+
+func (m *MockMetricsCollector) CollectPaxcount(pax domain.Paxcount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Paxcounts = append(m.Paxcounts, pax)
+	return nil
+}
+
+func (m *MockMetricsCollector) CollectRangeTest(packet domain.RangeTestPacket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RangeTestPackets = append(m.RangeTestPackets, packet)
+	return nil
+}
+
+func (m *MockMetricsCollector) CollectMappedValues(values []domain.MappedValue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MappedValues = append(m.MappedValues, values...)
+}
+
+func (m *MockMetricsCollector) MappingRegistered(mapping domain.MetricMapping) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !slices.Contains(m.SkippedMappings, mapping.MetricName())
+}
+
 func (m *MockMetricsCollector) GetRegistry() *prometheus.Registry {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -101,6 +175,45 @@ func (m *MockMetricsCollector) UpdateMessageCounter(nodeID string, messageType s
 	defer m.mu.Unlock()
 }
 
+func (m *MockMetricsCollector) UpdateUndecryptableCounter(channel string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.UndecryptableChannels = append(m.UndecryptableChannels, channel)
+}
+
+func (m *MockMetricsCollector) CollectReception(r domain.Reception) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Receptions = append(m.Receptions, r)
+}
+
+func (m *MockMetricsCollector) UpdateDuplicateCounter(gatewayID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.DuplicateGateways = append(m.DuplicateGateways, gatewayID)
+}
+
+func (m *MockMetricsCollector) CollectPacketHops(nodeID string, hopsAway int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.PacketHops == nil {
+		m.PacketHops = make(map[string]int)
+	}
+	m.PacketHops[nodeID] = hopsAway
+}
+
+func (m *MockMetricsCollector) UpdateRelayCounter(relayNode string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RelayNodes = append(m.RelayNodes, relayNode)
+}
+
+func (m *MockMetricsCollector) CollectDelivery(stats domain.DeliveryStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.DeliveryStats = append(m.DeliveryStats, stats)
+}
+
 func (m *MockMetricsCollector) GetNodeInfos() []domain.NodeInfo {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -227,6 +340,51 @@ func (m *MockMetricsCollectorWithErrors) CollectNeighborInfo(ni domain.NeighborI
 	return nil
 }
 
+func (m *MockMetricsCollectorWithErrors) CollectMapReport(report domain.MapReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return nil
+}
+
+func (m *MockMetricsCollectorWithErrors) CollectTraceroute(tr domain.Traceroute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return nil
+}
+
+func (m *MockMetricsCollectorWithErrors) CollectRoutingResult(result domain.RoutingResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return nil
+}
+
+func (m *MockMetricsCollectorWithErrors) CollectDetection(event domain.DetectionEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return nil
+}
+
+func (m *MockMetricsCollectorWithErrors) CollectPaxcount(pax domain.Paxcount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return nil
+}
+
+func (m *MockMetricsCollectorWithErrors) CollectRangeTest(packet domain.RangeTestPacket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return nil
+}
+
+func (m *MockMetricsCollectorWithErrors) CollectMappedValues(values []domain.MappedValue) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+}
+
+func (m *MockMetricsCollectorWithErrors) MappingRegistered(mapping domain.MetricMapping) bool {
+	return true
+}
+
 // MockAlertSender базовый mock для AlertSender
 type MockAlertSender struct {
 	SendAlertCalled bool
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+// topicPlaceholders плейсхолдеры шаблона топика и поле TopicInfo, в которое попадает сегмент.
+var topicPlaceholders = map[string]func(info *domain.TopicInfo, value string){
+	"region":  func(info *domain.TopicInfo, value string) { info.Region = value },
+	"version": func(info *domain.TopicInfo, value string) { info.Version = value },
+	"channel": func(info *domain.TopicInfo, value string) { info.Channel = value },
+	"gateway": func(info *domain.TopicInfo, value string) { info.Gateway = value },
+}
+
+// TopicTemplate шаблон топика вида msh/{region}/{version}/+/{channel}/{gateway}.
+// Сегмент "+" совпадает с любым значением, остальные сегменты должны совпадать буквально.
+type TopicTemplate struct {
+	segments []string
+}
+
+func ParseTopicTemplate(template string) (*TopicTemplate, error) {
+	if template == "" {
+		return nil, nil
+	}
+
+	segments := strings.Split(template, "/")
+	for _, segment := range segments {
+		if segment == "" || segment == "#" {
+			return nil, fmt.Errorf("invalid topic template segment %q", segment)
+		}
+		if name, ok := placeholderName(segment); ok {
+			if _, known := topicPlaceholders[name]; !known {
+				return nil, fmt.Errorf("unknown topic template placeholder {%s}", name)
+			}
+		}
+	}
+
+	return &TopicTemplate{segments: segments}, nil
+}
+
+// Parse разбирает топик по шаблону, false если топик не подходит под шаблон.
+func (t *TopicTemplate) Parse(topic string) (domain.TopicInfo, bool) {
+	var info domain.TopicInfo
+	if t == nil {
+		return info, false
+	}
+
+	segments := strings.Split(topic, "/")
+	if len(segments) != len(t.segments) {
+		return info, false
+	}
+
+	for i, pattern := range t.segments {
+		if name, ok := placeholderName(pattern); ok {
+			topicPlaceholders[name](&info, segments[i])
+			continue
+		}
+		if pattern != "+" && pattern != segments[i] {
+			return domain.TopicInfo{}, false
+		}
+	}
+
+	return info, true
+}
+
+func placeholderName(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
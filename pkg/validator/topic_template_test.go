@@ -0,0 +1,81 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"meshtastic-exporter/pkg/domain"
+)
+
+func TestParseTopicTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"default template", domain.DefaultTopicTemplate, false},
+		{"literal segments", "msh/{region}/2/json/{channel}/{gateway}", false},
+		{"unknown placeholder", "msh/{region}/{city}/json/{channel}", true},
+		{"multi-level wildcard", "msh/{region}/#", true},
+		{"empty segment", "msh//{channel}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseTopicTemplate(tt.template)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseTopicTemplate_Empty(t *testing.T) {
+	t.Parallel()
+	template, err := ParseTopicTemplate("")
+
+	require.NoError(t, err)
+	assert.Nil(t, template)
+
+	_, ok := template.Parse("msh/EU_868/2/json/LongFast/!abcd1234")
+	assert.False(t, ok)
+}
+
+func TestTopicTemplate_Parse(t *testing.T) {
+	t.Parallel()
+	template, err := ParseTopicTemplate("msh/{region}/{version}/json/{channel}/{gateway}")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		topic string
+		want  domain.TopicInfo
+		ok    bool
+	}{
+		{
+			name:  "json topic",
+			topic: "msh/EU_868/2/json/LongFast/!abcd1234",
+			want:  domain.TopicInfo{Region: "EU_868", Version: "2", Channel: "LongFast", Gateway: "!abcd1234"},
+			ok:    true,
+		},
+		{name: "literal mismatch", topic: "msh/EU_868/2/e/LongFast/!abcd1234"},
+		{name: "too short", topic: "msh/EU_868/2/json/LongFast"},
+		{name: "too long", topic: "msh/EU_868/2/json/LongFast/!abcd1234/extra"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			info, ok := template.Parse(tt.topic)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, info)
+		})
+	}
+}
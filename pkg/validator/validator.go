@@ -3,18 +3,35 @@ package validator
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"meshtastic-exporter/pkg/domain"
 )
 
 func ValidateMeshtasticMessage(payload []byte) error {
-	if len(payload) == 0 {
-		return fmt.Errorf("empty payload")
+	if err := ValidateMessageFormat(payload); err != nil {
+		return err
 	}
 
-	if len(payload) > 1024*1024 { // 1MB limit
-		return fmt.Errorf("payload too large: %d bytes", len(payload))
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	// Check required fields
+	if _, ok := data["from"]; !ok {
+		return fmt.Errorf("missing 'from' field")
+	}
+
+	return nil
+}
+
+// ValidateMessageFormat размер и похожесть на JSON без разбора: сообщение
+// разбирается один раз уже в процессоре.
+func ValidateMessageFormat(payload []byte) error {
+	if err := validatePayloadSize(payload); err != nil {
+		return err
 	}
 
 	// Check if payload looks like JSON
@@ -22,14 +39,21 @@ func ValidateMeshtasticMessage(payload []byte) error {
 		return fmt.Errorf("not JSON format")
 	}
 
-	var data map[string]interface{}
-	if err := json.Unmarshal(payload, &data); err != nil {
-		return fmt.Errorf("invalid JSON: %w", err)
+	return nil
+}
+
+// ValidateProtobufMessage проверяет бинарный ServiceEnvelope до декодирования.
+func ValidateProtobufMessage(payload []byte) error {
+	return validatePayloadSize(payload)
+}
+
+func validatePayloadSize(payload []byte) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("empty payload")
 	}
 
-	// Check required fields
-	if _, ok := data["from"]; !ok {
-		return fmt.Errorf("missing 'from' field")
+	if len(payload) > 1024*1024 { // 1MB limit
+		return fmt.Errorf("payload too large: %d bytes", len(payload))
 	}
 
 	return nil
@@ -80,6 +104,14 @@ func ValidateNodeID(nodeID string) error {
 		return fmt.Errorf("node ID too long: %d chars", len(nodeID))
 	}
 
+	// hex ID вида !f992bd54
+	if strings.HasPrefix(nodeID, "!") {
+		if _, err := strconv.ParseUint(nodeID[1:], 16, 32); err != nil || len(nodeID) != 9 {
+			return fmt.Errorf("invalid node ID format: %s", nodeID)
+		}
+		return nil
+	}
+
 	// numeric ID
 	for _, r := range nodeID {
 		if r < '0' || r > '9' {
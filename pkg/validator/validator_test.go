@@ -26,6 +26,31 @@ func TestValidateMeshtasticMessage(t *testing.T) {
 	}
 }
 
+func TestValidateMessageFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		payload []byte
+		wantErr bool
+	}{
+		{"object", []byte(`  {"from":123}`), false},
+		{"invalid JSON is not parsed", []byte(`{invalid`), false},
+		{"empty payload", []byte(``), true},
+		{"whitespace only", []byte(" \n\t"), true},
+		{"non-JSON", []byte(`plain text`), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateMessageFormat(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMessageFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateTopicName(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -57,6 +82,9 @@ func TestValidateNodeID(t *testing.T) {
 		wantErr bool
 	}{
 		{"valid node ID", "123456789", false},
+		{"valid hex node ID", "!f992bd54", false},
+		{"short hex node ID", "!f992", true},
+		{"invalid hex node ID", "!f992bdzz", true},
 		{"empty node ID", "", true},
 		{"too long node ID", string(make([]byte, 50)), true},
 	}